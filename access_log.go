@@ -0,0 +1,343 @@
+/*
+ * Copyright 2016 Xuyuan Pang
+ * Author: Xuyuan Pang
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package hodor
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+)
+
+// AccessRecord is the record of a request and its response.
+type AccessRecord struct {
+	Time   time.Time
+	Method string
+	// Route is the full pattern of the matched route, see
+	// RequestRecorder.Pattern, or empty if no route matched.
+	Route string
+	Path  string
+	// Query is the raw query, without '?'.
+	Query string
+	Proto string
+	// Status is the status code of the response.
+	Status int
+	// Size is the size of the response body, by ResponseWriter.Size.
+	Size       int
+	Duration   time.Duration
+	RemoteAddr string
+	// User is the user name of basic authentication.
+	User      string
+	UserAgent string
+	Referer   string
+	RequestID string
+}
+
+// RequestURI returns the path and the query of the request.
+func (rec *AccessRecord) RequestURI() string {
+	if rec.Query == "" {
+		return rec.Path
+	}
+	return rec.Path + "?" + rec.Query
+}
+
+// AccessLogFormat writes rec to w, without the trailing newline.
+type AccessLogFormat func(w io.Writer, rec *AccessRecord) error
+
+// AccessLogJSON formats records as JSON objects, with the duration in
+// milliseconds.
+var AccessLogJSON AccessLogFormat = func(w io.Writer, rec *AccessRecord) error {
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	return enc.Encode(struct {
+		Time       string  `json:"time"`
+		Method     string  `json:"method"`
+		Route      string  `json:"route,omitempty"`
+		Path       string  `json:"path"`
+		Query      string  `json:"query,omitempty"`
+		Proto      string  `json:"proto"`
+		Status     int     `json:"status"`
+		Size       int     `json:"size"`
+		Duration   float64 `json:"duration_ms"`
+		RemoteAddr string  `json:"remote_addr"`
+		User       string  `json:"user,omitempty"`
+		UserAgent  string  `json:"user_agent,omitempty"`
+		Referer    string  `json:"referer,omitempty"`
+		RequestID  string  `json:"request_id,omitempty"`
+	}{
+		Time:       rec.Time.Format(time.RFC3339Nano),
+		Method:     rec.Method,
+		Route:      rec.Route,
+		Path:       rec.Path,
+		Query:      rec.Query,
+		Proto:      rec.Proto,
+		Status:     rec.Status,
+		Size:       rec.Size,
+		Duration:   float64(rec.Duration) / float64(time.Millisecond),
+		RemoteAddr: rec.RemoteAddr,
+		User:       rec.User,
+		UserAgent:  rec.UserAgent,
+		Referer:    rec.Referer,
+		RequestID:  rec.RequestID,
+	})
+}
+
+// AccessLogCommon formats records in the Apache Common Log Format.
+var AccessLogCommon AccessLogFormat = func(w io.Writer, rec *AccessRecord) error {
+	size := "-"
+	if rec.Size > 0 {
+		size = strconv.Itoa(rec.Size)
+	}
+	_, err := fmt.Fprintf(w, `%s - %s [%s] "%s %s %s" %d %s`,
+		rec.RemoteAddr,
+		clfField(rec.User),
+		rec.Time.Format("02/Jan/2006:15:04:05 -0700"),
+		rec.Method, clfEscape(rec.RequestURI()), rec.Proto,
+		rec.Status, size)
+	return err
+}
+
+// AccessLogCombined formats records in the Apache Combined Log Format.
+var AccessLogCombined AccessLogFormat = func(w io.Writer, rec *AccessRecord) error {
+	if err := AccessLogCommon(w, rec); err != nil {
+		return err
+	}
+	_, err := fmt.Fprintf(w, ` "%s" "%s"`, clfEscape(rec.Referer), clfEscape(rec.UserAgent))
+	return err
+}
+
+// AccessLogTemplate returns the format executing the text/template text
+// with *AccessRecord, like "{{.Method}} {{.Route}} {{.Status}}".
+func AccessLogTemplate(text string) (AccessLogFormat, error) {
+	tmpl, err := template.New("access").Parse(text)
+	if err != nil {
+		return nil, err
+	}
+	return func(w io.Writer, rec *AccessRecord) error {
+		return tmpl.Execute(w, rec)
+	}, nil
+}
+
+func clfField(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return clfEscape(s)
+}
+
+// clfEscape escapes quotes, backslashes and non-printable characters like
+// Apache does.
+func clfEscape(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case c == '"' || c == '\\':
+			b.WriteByte('\\')
+			b.WriteByte(c)
+		case c < 0x20 || c >= 0x7f:
+			fmt.Fprintf(&b, `\x%02x`, c)
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
+
+// AccessLogOptions configures AccessLogFilter.
+type AccessLogOptions struct {
+	// Output receives records, one per line, os.Stdout if nil.
+	Output io.Writer
+	// Format formats records, AccessLogJSON if nil.
+	Format AccessLogFormat
+	// SampleRate is the ratio of records logged, from 0 to 1, or 1 if zero.
+	// Records of server errors are always logged.
+	SampleRate float64
+	// ExcludedPaths are paths not logged, like "/healthz", or prefixes of
+	// paths not logged if ending with "*", like "/debug/*".
+	ExcludedPaths []string
+	// TrustedProxies are IPs or CIDRs, like "10.0.0.0/8", of proxies whose
+	// X-Forwarded-For and X-Real-IP headers are trusted to get the client
+	// address.
+	TrustedProxies []string
+}
+
+type accessLog struct {
+	AccessLogOptions
+	mu      sync.Mutex
+	proxies []*net.IPNet
+	bufs    sync.Pool
+}
+
+// AccessLogFilter new filter logging a record per request, an alternative
+// to LogFilter for structured logs. It panics if a trusted proxy is invalid.
+func AccessLogFilter(opts AccessLogOptions) FilterFunc {
+	al := &accessLog{AccessLogOptions: opts}
+	if al.Output == nil {
+		al.Output = os.Stdout
+	}
+	if al.Format == nil {
+		al.Format = AccessLogJSON
+	}
+	if al.SampleRate == 0 {
+		al.SampleRate = 1
+	}
+	for _, proxy := range opts.TrustedProxies {
+		if !strings.Contains(proxy, "/") {
+			if strings.Contains(proxy, ":") {
+				proxy += "/128"
+			} else {
+				proxy += "/32"
+			}
+		}
+		_, ipNet, err := net.ParseCIDR(proxy)
+		if err != nil {
+			panic(err)
+		}
+		al.proxies = append(al.proxies, ipNet)
+	}
+	al.bufs.New = func() interface{} { return new(bytes.Buffer) }
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(
+			func(w http.ResponseWriter, r *http.Request) {
+				if al.excluded(r.URL.Path) {
+					next.ServeHTTP(w, r)
+					return
+				}
+				rw, ok := w.(ResponseWriter)
+				if !ok {
+					rw = NewResponseWriter(w)
+				}
+				ctx, rec := WithRequestRecorder(r.Context())
+				if ctx != r.Context() {
+					r = r.WithContext(ctx)
+				}
+				start := time.Now()
+				next.ServeHTTP(rw, r)
+				al.log(r, rw, rec, start)
+			})
+	}
+}
+
+func (al *accessLog) excluded(p string) bool {
+	for _, excluded := range al.ExcludedPaths {
+		if strings.HasSuffix(excluded, "*") {
+			if strings.HasPrefix(p, excluded[:len(excluded)-1]) {
+				return true
+			}
+		} else if p == excluded {
+			return true
+		}
+	}
+	return false
+}
+
+func (al *accessLog) log(r *http.Request, w ResponseWriter, rec *RequestRecorder, start time.Time) {
+	status := w.Status()
+	if status == 0 {
+		status = http.StatusOK
+	}
+	if al.SampleRate < 1 && status < 500 && rand.Float64() >= al.SampleRate {
+		return
+	}
+	user, _, _ := r.BasicAuth()
+	record := &AccessRecord{
+		Time:       start,
+		Method:     r.Method,
+		Path:       r.URL.Path,
+		Query:      r.URL.RawQuery,
+		Proto:      r.Proto,
+		Status:     status,
+		Size:       w.Size(),
+		Duration:   time.Since(start),
+		RemoteAddr: al.remoteAddr(r),
+		User:       user,
+		UserAgent:  r.UserAgent(),
+		Referer:    r.Referer(),
+		Route:      rec.Pattern(),
+		RequestID:  rec.RequestID(),
+	}
+	if record.RequestID == "" {
+		record.RequestID = RequestIDOfReq(r)
+	}
+
+	buf := al.bufs.Get().(*bytes.Buffer)
+	buf.Reset()
+	if err := al.Format(buf, record); err == nil {
+		if b := buf.Bytes(); len(b) == 0 || b[len(b)-1] != '\n' {
+			buf.WriteByte('\n')
+		}
+		al.mu.Lock()
+		al.Output.Write(buf.Bytes())
+		al.mu.Unlock()
+	}
+	al.bufs.Put(buf)
+}
+
+// remoteAddr returns the client address. Proxy headers are only used if
+// the request comes from a trusted proxy, and then the last address of
+// X-Forwarded-For not of a trusted proxy is the client.
+func (al *accessLog) remoteAddr(r *http.Request) string {
+	addr, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		addr = r.RemoteAddr
+	}
+	if !al.trusted(addr) {
+		return addr
+	}
+	if xff := r.Header.Get("X-Forwarded-For"); xff != "" {
+		hops := strings.Split(xff, ",")
+		for i := len(hops) - 1; i >= 0; i-- {
+			hop := strings.TrimSpace(hops[i])
+			if hop == "" {
+				continue
+			}
+			addr = hop
+			if !al.trusted(hop) {
+				break
+			}
+		}
+		return addr
+	}
+	if ip := r.Header.Get("X-Real-IP"); ip != "" {
+		return ip
+	}
+	return addr
+}
+
+func (al *accessLog) trusted(addr string) bool {
+	ip := net.ParseIP(addr)
+	if ip == nil {
+		return false
+	}
+	for _, proxy := range al.proxies {
+		if proxy.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
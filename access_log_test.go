@@ -0,0 +1,225 @@
+package hodor
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAccessLogFilter(t *testing.T) {
+	var buf bytes.Buffer
+	h := NewHodor(NewRouter())
+	h.Route().Get().Pattern("/users/:id").HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("hodor"))
+	})
+	h.SetFilters(
+		AccessLogFilter(AccessLogOptions{Output: &buf}),
+		RequestIDFilter(RequestIDOptions{}),
+	)
+
+	req := httptest.NewRequest("GET", "/users/42?verbose=1", nil)
+	req.Header.Set("X-Request-ID", "req-1")
+	req.Header.Set("User-Agent", "hodor-test")
+	h.ServeHTTP(httptest.NewRecorder(), req)
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/missing", nil))
+
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("should log a line per request, got: %q", buf.String())
+	}
+	var rec map[string]interface{}
+	if err := json.Unmarshal([]byte(lines[0]), &rec); err != nil {
+		t.Fatalf("record should be JSON: %v", err)
+	}
+	exp := map[string]interface{}{
+		"method":      "GET",
+		"route":       "/users/:id",
+		"path":        "/users/42",
+		"query":       "verbose=1",
+		"status":      200.0,
+		"size":        5.0,
+		"remote_addr": "192.0.2.1",
+		"user_agent":  "hodor-test",
+		"request_id":  "req-1",
+	}
+	for key, value := range exp {
+		if rec[key] != value {
+			t.Errorf("%s not match. exp: %v, got: %v", key, value, rec[key])
+		}
+	}
+	rec = nil
+	if err := json.Unmarshal([]byte(lines[1]), &rec); err != nil {
+		t.Fatalf("record should be JSON: %v", err)
+	}
+	if _, ok := rec["route"]; ok || rec["status"] != 404.0 {
+		t.Errorf("unmatched request should have status 404 and no route, got: %s", lines[1])
+	}
+}
+
+func TestAccessLogFormats(t *testing.T) {
+	rec := &AccessRecord{
+		Time:       time.Date(2000, 10, 10, 13, 55, 36, 0, time.FixedZone("", -7*3600)),
+		Method:     "GET",
+		Route:      "/apache_pb.gif",
+		Path:       "/apache_pb.gif",
+		Proto:      "HTTP/1.0",
+		Status:     200,
+		Size:       2326,
+		Duration:   1500 * time.Microsecond,
+		RemoteAddr: "127.0.0.1",
+		User:       "frank",
+		UserAgent:  `Mozilla/4.08 "quoted"`,
+		Referer:    "http://www.example.com/start.html",
+	}
+	tmpl, err := AccessLogTemplate("{{.Method}} {{.Route}} {{.Status}} {{.Duration}}")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		name   string
+		format AccessLogFormat
+		exp    string
+	}{
+		{"common", AccessLogCommon,
+			`127.0.0.1 - frank [10/Oct/2000:13:55:36 -0700] "GET /apache_pb.gif HTTP/1.0" 200 2326`},
+		{"combined", AccessLogCombined,
+			`127.0.0.1 - frank [10/Oct/2000:13:55:36 -0700] "GET /apache_pb.gif HTTP/1.0" 200 2326 "http://www.example.com/start.html" "Mozilla/4.08 \"quoted\""`},
+		{"template", tmpl, "GET /apache_pb.gif 200 1.5ms"},
+	}
+	for _, c := range cases {
+		var buf bytes.Buffer
+		if err := c.format(&buf, rec); err != nil {
+			t.Errorf("%s format failed: %v", c.name, err)
+		}
+		if got := buf.String(); got != c.exp {
+			t.Errorf("%s format not match.\nexp: %s\ngot: %s", c.name, c.exp, got)
+		}
+	}
+
+	if _, err := AccessLogTemplate("{{.Method"); err == nil {
+		t.Error("invalid template should fail")
+	}
+}
+
+func TestAccessLogExclusionAndSampling(t *testing.T) {
+	var buf bytes.Buffer
+	handler := AccessLogFilter(AccessLogOptions{
+		Output:        &buf,
+		Format:        AccessLogCommon,
+		SampleRate:    1e-9,
+		ExcludedPaths: []string{"/healthz", "/debug/*"},
+	}).Do(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/fail" {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+
+	for _, p := range []string{"/healthz", "/debug/pprof", "/", "/fail"} {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", p, nil))
+	}
+	if got := buf.String(); strings.Count(got, "\n") != 1 || !strings.Contains(got, `"GET /fail HTTP/1.1" 500 -`) {
+		t.Errorf("only the server error should be logged, got: %q", got)
+	}
+}
+
+func TestAccessLogRemoteAddr(t *testing.T) {
+	var buf bytes.Buffer
+	format, _ := AccessLogTemplate("{{.RemoteAddr}}")
+	handler := AccessLogFilter(AccessLogOptions{
+		Output:         &buf,
+		Format:         format,
+		TrustedProxies: []string{"10.0.0.0/8", "192.0.2.1"},
+	}).Do(defaultHandler)
+
+	cases := []struct {
+		remote, xff, realIP, exp string
+	}{
+		{"203.0.113.9:1234", "1.2.3.4", "5.6.7.8", "203.0.113.9"},
+		{"192.0.2.1:1234", "1.2.3.4, 10.0.0.2", "", "1.2.3.4"},
+		{"192.0.2.1:1234", "6.6.6.6, 1.2.3.4, 10.0.0.2", "", "1.2.3.4"},
+		{"10.0.0.1:1234", "10.0.0.3", "", "10.0.0.3"},
+		{"10.0.0.1:1234", "", "1.2.3.4", "1.2.3.4"},
+		{"10.0.0.1:1234", "", "", "10.0.0.1"},
+	}
+	for _, c := range cases {
+		buf.Reset()
+		req := httptest.NewRequest("GET", "/", nil)
+		req.RemoteAddr = c.remote
+		if c.xff != "" {
+			req.Header.Set("X-Forwarded-For", c.xff)
+		}
+		if c.realIP != "" {
+			req.Header.Set("X-Real-IP", c.realIP)
+		}
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+		if got := strings.TrimSpace(buf.String()); got != c.exp {
+			t.Errorf("remote address of %s (%q) not match. exp: %s, got: %s", c.remote, c.xff, c.exp, got)
+		}
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("invalid trusted proxy should panic")
+		}
+	}()
+	AccessLogFilter(AccessLogOptions{TrustedProxies: []string{"proxy"}})
+}
+
+func TestAccessLogMountedRoute(t *testing.T) {
+	v1 := NewRouter()
+	v1.AddRoute(GET, "/items/:id", defaultHandler)
+	admin := NewHodor(NewRouter())
+	admin.Route().Get().Pattern("/").Handler(defaultHandler)
+	admin.Route().Get().Pattern("/users/:id").Handler(defaultHandler)
+	admin.Route().Mount("/v1", v1)
+
+	var buf bytes.Buffer
+	format, _ := AccessLogTemplate("{{.Route}}")
+	h := NewHodor(NewRouter())
+	h.Route().Mount("/admin", admin)
+	h.Route().Mount("/static", http.NotFoundHandler())
+	h.SetFilters(AccessLogFilter(AccessLogOptions{Output: &buf, Format: format}))
+
+	cases := []struct {
+		path  string
+		route string
+	}{
+		{"/admin/users/42", "/admin/users/:id"},
+		{"/admin", "/admin"},
+		{"/admin/", "/admin/"},
+		{"/admin/v1/items/42", "/admin/v1/items/:id"},
+		{"/static/css/main.css", "/static/*mountpath"},
+	}
+	for _, c := range cases {
+		buf.Reset()
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", c.path, nil))
+		if got := strings.TrimSpace(buf.String()); got != c.route {
+			t.Errorf("GET %s route not match. exp: %s, got: %s", c.path, c.route, got)
+		}
+	}
+}
+
+func TestAccessLogDefaultOutput(t *testing.T) {
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout := os.Stdout
+	os.Stdout = w
+	filter := AccessLogFilter(AccessLogOptions{})
+	os.Stdout = stdout
+
+	filter.Do(defaultHandler).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+	w.Close()
+	b, _ := ioutil.ReadAll(r)
+	if !bytes.Contains(b, []byte(`"path":"/"`)) {
+		t.Errorf("record should be written to stdout, got: %q", b)
+	}
+}
@@ -0,0 +1,436 @@
+package hodor
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// Route sets of real world APIs, after the GitHub, Parse and Google+ APIs
+// used by go-http-routing-benchmark.
+
+type benchRoute struct {
+	method Method
+	path   string
+}
+
+var githubAPI = []benchRoute{
+	// OAuth Authorizations
+	{GET, "/authorizations"},
+	{GET, "/authorizations/:id"},
+	{POST, "/authorizations"},
+	{PUT, "/authorizations/clients/:client_id"},
+	{PATCH, "/authorizations/:id"},
+	{DELETE, "/authorizations/:id"},
+	{GET, "/applications/:client_id/tokens/:access_token"},
+	{DELETE, "/applications/:client_id/tokens"},
+	{DELETE, "/applications/:client_id/tokens/:access_token"},
+
+	// Activity
+	{GET, "/events"},
+	{GET, "/repos/:owner/:repo/events"},
+	{GET, "/networks/:owner/:repo/events"},
+	{GET, "/orgs/:org/events"},
+	{GET, "/users/:user/received_events"},
+	{GET, "/users/:user/received_events/public"},
+	{GET, "/users/:user/events"},
+	{GET, "/users/:user/events/public"},
+	{GET, "/users/:user/events/orgs/:org"},
+	{GET, "/feeds"},
+	{GET, "/notifications"},
+	{GET, "/repos/:owner/:repo/notifications"},
+	{PUT, "/notifications"},
+	{PUT, "/repos/:owner/:repo/notifications"},
+	{GET, "/notifications/threads/:id"},
+	{PATCH, "/notifications/threads/:id"},
+	{GET, "/notifications/threads/:id/subscription"},
+	{PUT, "/notifications/threads/:id/subscription"},
+	{DELETE, "/notifications/threads/:id/subscription"},
+	{GET, "/repos/:owner/:repo/stargazers"},
+	{GET, "/users/:user/starred"},
+	{GET, "/user/starred"},
+	{GET, "/user/starred/:owner/:repo"},
+	{PUT, "/user/starred/:owner/:repo"},
+	{DELETE, "/user/starred/:owner/:repo"},
+	{GET, "/repos/:owner/:repo/subscribers"},
+	{GET, "/users/:user/subscriptions"},
+	{GET, "/user/subscriptions"},
+	{GET, "/repos/:owner/:repo/subscription"},
+	{PUT, "/repos/:owner/:repo/subscription"},
+	{DELETE, "/repos/:owner/:repo/subscription"},
+	{GET, "/user/subscriptions/:owner/:repo"},
+	{PUT, "/user/subscriptions/:owner/:repo"},
+	{DELETE, "/user/subscriptions/:owner/:repo"},
+
+	// Gists
+	{GET, "/users/:user/gists"},
+	{GET, "/gists"},
+	{GET, "/gists/public"},
+	{GET, "/gists/starred"},
+	{GET, "/gists/:id"},
+	{POST, "/gists"},
+	{PATCH, "/gists/:id"},
+	{PUT, "/gists/:id/star"},
+	{DELETE, "/gists/:id/star"},
+	{GET, "/gists/:id/star"},
+	{POST, "/gists/:id/forks"},
+	{DELETE, "/gists/:id"},
+
+	// Git Data
+	{GET, "/repos/:owner/:repo/git/blobs/:sha"},
+	{POST, "/repos/:owner/:repo/git/blobs"},
+	{GET, "/repos/:owner/:repo/git/commits/:sha"},
+	{POST, "/repos/:owner/:repo/git/commits"},
+	{GET, "/repos/:owner/:repo/git/refs/*ref"},
+	{GET, "/repos/:owner/:repo/git/refs"},
+	{POST, "/repos/:owner/:repo/git/refs"},
+	{PATCH, "/repos/:owner/:repo/git/refs/*ref"},
+	{DELETE, "/repos/:owner/:repo/git/refs/*ref"},
+	{GET, "/repos/:owner/:repo/git/tags/:sha"},
+	{POST, "/repos/:owner/:repo/git/tags"},
+	{GET, "/repos/:owner/:repo/git/trees/:sha"},
+	{POST, "/repos/:owner/:repo/git/trees"},
+
+	// Issues
+	{GET, "/issues"},
+	{GET, "/user/issues"},
+	{GET, "/orgs/:org/issues"},
+	{GET, "/repos/:owner/:repo/issues"},
+	{GET, "/repos/:owner/:repo/issues/:number"},
+	{POST, "/repos/:owner/:repo/issues"},
+	{PATCH, "/repos/:owner/:repo/issues/:number"},
+	{GET, "/repos/:owner/:repo/assignees"},
+	{GET, "/repos/:owner/:repo/assignees/:assignee"},
+	{GET, "/repos/:owner/:repo/issues/:number/comments"},
+	{POST, "/repos/:owner/:repo/issues/:number/comments"},
+	{GET, "/repos/:owner/:repo/issues/:number/events"},
+	{GET, "/repos/:owner/:repo/labels"},
+	{GET, "/repos/:owner/:repo/labels/:name"},
+	{POST, "/repos/:owner/:repo/labels"},
+	{PATCH, "/repos/:owner/:repo/labels/:name"},
+	{DELETE, "/repos/:owner/:repo/labels/:name"},
+	{GET, "/repos/:owner/:repo/issues/:number/labels"},
+	{POST, "/repos/:owner/:repo/issues/:number/labels"},
+	{DELETE, "/repos/:owner/:repo/issues/:number/labels/:name"},
+	{PUT, "/repos/:owner/:repo/issues/:number/labels"},
+	{DELETE, "/repos/:owner/:repo/issues/:number/labels"},
+	{GET, "/repos/:owner/:repo/milestones/:number/labels"},
+	{GET, "/repos/:owner/:repo/milestones"},
+	{GET, "/repos/:owner/:repo/milestones/:number"},
+	{POST, "/repos/:owner/:repo/milestones"},
+	{PATCH, "/repos/:owner/:repo/milestones/:number"},
+	{DELETE, "/repos/:owner/:repo/milestones/:number"},
+
+	// Miscellaneous
+	{GET, "/emojis"},
+	{GET, "/gitignore/templates"},
+	{GET, "/gitignore/templates/:name"},
+	{POST, "/markdown"},
+	{POST, "/markdown/raw"},
+	{GET, "/meta"},
+	{GET, "/rate_limit"},
+
+	// Organizations
+	{GET, "/users/:user/orgs"},
+	{GET, "/user/orgs"},
+	{GET, "/orgs/:org"},
+	{PATCH, "/orgs/:org"},
+	{GET, "/orgs/:org/members"},
+	{GET, "/orgs/:org/members/:user"},
+	{DELETE, "/orgs/:org/members/:user"},
+	{GET, "/orgs/:org/public_members"},
+	{GET, "/orgs/:org/public_members/:user"},
+	{PUT, "/orgs/:org/public_members/:user"},
+	{DELETE, "/orgs/:org/public_members/:user"},
+	{GET, "/orgs/:org/teams"},
+	{GET, "/teams/:id"},
+	{POST, "/orgs/:org/teams"},
+	{PATCH, "/teams/:id"},
+	{DELETE, "/teams/:id"},
+	{GET, "/teams/:id/members"},
+	{GET, "/teams/:id/members/:user"},
+	{PUT, "/teams/:id/members/:user"},
+	{DELETE, "/teams/:id/members/:user"},
+	{GET, "/teams/:id/repos"},
+	{GET, "/teams/:id/repos/:owner/:repo"},
+	{PUT, "/teams/:id/repos/:owner/:repo"},
+	{DELETE, "/teams/:id/repos/:owner/:repo"},
+	{GET, "/user/teams"},
+
+	// Pull Requests
+	{GET, "/repos/:owner/:repo/pulls"},
+	{GET, "/repos/:owner/:repo/pulls/:number"},
+	{POST, "/repos/:owner/:repo/pulls"},
+	{PATCH, "/repos/:owner/:repo/pulls/:number"},
+	{GET, "/repos/:owner/:repo/pulls/:number/commits"},
+	{GET, "/repos/:owner/:repo/pulls/:number/files"},
+	{GET, "/repos/:owner/:repo/pulls/:number/merge"},
+	{PUT, "/repos/:owner/:repo/pulls/:number/merge"},
+	{GET, "/repos/:owner/:repo/pulls/:number/comments"},
+	{PUT, "/repos/:owner/:repo/pulls/:number/comments"},
+
+	// Repositories
+	{GET, "/user/repos"},
+	{GET, "/users/:user/repos"},
+	{GET, "/orgs/:org/repos"},
+	{GET, "/repositories"},
+	{POST, "/user/repos"},
+	{POST, "/orgs/:org/repos"},
+	{GET, "/repos/:owner/:repo"},
+	{PATCH, "/repos/:owner/:repo"},
+	{GET, "/repos/:owner/:repo/contributors"},
+	{GET, "/repos/:owner/:repo/languages"},
+	{GET, "/repos/:owner/:repo/teams"},
+	{GET, "/repos/:owner/:repo/tags"},
+	{GET, "/repos/:owner/:repo/branches"},
+	{GET, "/repos/:owner/:repo/branches/:branch"},
+	{DELETE, "/repos/:owner/:repo"},
+	{GET, "/repos/:owner/:repo/collaborators"},
+	{GET, "/repos/:owner/:repo/collaborators/:user"},
+	{PUT, "/repos/:owner/:repo/collaborators/:user"},
+	{DELETE, "/repos/:owner/:repo/collaborators/:user"},
+	{GET, "/repos/:owner/:repo/comments"},
+	{GET, "/repos/:owner/:repo/commits/:sha/comments"},
+	{POST, "/repos/:owner/:repo/commits/:sha/comments"},
+	{GET, "/repos/:owner/:repo/comments/:id"},
+	{PATCH, "/repos/:owner/:repo/comments/:id"},
+	{DELETE, "/repos/:owner/:repo/comments/:id"},
+	{GET, "/repos/:owner/:repo/commits"},
+	{GET, "/repos/:owner/:repo/commits/:sha"},
+	{GET, "/repos/:owner/:repo/readme"},
+	{GET, "/repos/:owner/:repo/contents/*path"},
+	{PUT, "/repos/:owner/:repo/contents/*path"},
+	{DELETE, "/repos/:owner/:repo/contents/*path"},
+	{GET, "/repos/:owner/:repo/:archive_format/:ref"},
+	{GET, "/repos/:owner/:repo/keys"},
+	{GET, "/repos/:owner/:repo/keys/:id"},
+	{POST, "/repos/:owner/:repo/keys"},
+	{PATCH, "/repos/:owner/:repo/keys/:id"},
+	{DELETE, "/repos/:owner/:repo/keys/:id"},
+	{GET, "/repos/:owner/:repo/downloads"},
+	{GET, "/repos/:owner/:repo/downloads/:id"},
+	{DELETE, "/repos/:owner/:repo/downloads/:id"},
+	{GET, "/repos/:owner/:repo/forks"},
+	{POST, "/repos/:owner/:repo/forks"},
+	{GET, "/repos/:owner/:repo/hooks"},
+	{GET, "/repos/:owner/:repo/hooks/:id"},
+	{POST, "/repos/:owner/:repo/hooks"},
+	{PATCH, "/repos/:owner/:repo/hooks/:id"},
+	{POST, "/repos/:owner/:repo/hooks/:id/tests"},
+	{DELETE, "/repos/:owner/:repo/hooks/:id"},
+	{POST, "/repos/:owner/:repo/merges"},
+	{GET, "/repos/:owner/:repo/releases"},
+	{GET, "/repos/:owner/:repo/releases/:id"},
+	{POST, "/repos/:owner/:repo/releases"},
+	{PATCH, "/repos/:owner/:repo/releases/:id"},
+	{DELETE, "/repos/:owner/:repo/releases/:id"},
+	{GET, "/repos/:owner/:repo/releases/:id/assets"},
+	{GET, "/repos/:owner/:repo/stats/contributors"},
+	{GET, "/repos/:owner/:repo/stats/commit_activity"},
+	{GET, "/repos/:owner/:repo/stats/code_frequency"},
+	{GET, "/repos/:owner/:repo/stats/participation"},
+	{GET, "/repos/:owner/:repo/stats/punch_card"},
+	{GET, "/repos/:owner/:repo/statuses/:ref"},
+	{POST, "/repos/:owner/:repo/statuses/:ref"},
+
+	// Search
+	{GET, "/search/repositories"},
+	{GET, "/search/code"},
+	{GET, "/search/issues"},
+	{GET, "/search/users"},
+	{GET, "/legacy/issues/search/:owner/:repository/:state/:keyword"},
+	{GET, "/legacy/repos/search/:keyword"},
+	{GET, "/legacy/user/search/:keyword"},
+	{GET, "/legacy/user/email/:email"},
+
+	// Users
+	{GET, "/users/:user"},
+	{GET, "/user"},
+	{PATCH, "/user"},
+	{GET, "/users"},
+	{GET, "/user/emails"},
+	{POST, "/user/emails"},
+	{DELETE, "/user/emails"},
+	{GET, "/users/:user/followers"},
+	{GET, "/user/followers"},
+	{GET, "/users/:user/following"},
+	{GET, "/user/following"},
+	{GET, "/user/following/:user"},
+	{GET, "/users/:user/following/:target_user"},
+	{PUT, "/user/following/:user"},
+	{DELETE, "/user/following/:user"},
+	{GET, "/users/:user/keys"},
+	{GET, "/user/keys"},
+	{GET, "/user/keys/:id"},
+	{POST, "/user/keys"},
+	{PATCH, "/user/keys/:id"},
+	{DELETE, "/user/keys/:id"},
+}
+
+var parseAPI = []benchRoute{
+	// Objects
+	{POST, "/1/classes/:className"},
+	{GET, "/1/classes/:className/:objectId"},
+	{PUT, "/1/classes/:className/:objectId"},
+	{GET, "/1/classes/:className"},
+	{DELETE, "/1/classes/:className/:objectId"},
+
+	// Users
+	{POST, "/1/users"},
+	{GET, "/1/login"},
+	{GET, "/1/users/:objectId"},
+	{PUT, "/1/users/:objectId"},
+	{GET, "/1/users"},
+	{DELETE, "/1/users/:objectId"},
+	{POST, "/1/requestPasswordReset"},
+
+	// Roles
+	{POST, "/1/roles"},
+	{GET, "/1/roles/:objectId"},
+	{PUT, "/1/roles/:objectId"},
+	{GET, "/1/roles"},
+	{DELETE, "/1/roles/:objectId"},
+
+	// Files
+	{POST, "/1/files/:fileName"},
+
+	// Analytics
+	{POST, "/1/events/:eventName"},
+
+	// Push Notifications
+	{POST, "/1/push"},
+
+	// Installations
+	{POST, "/1/installations"},
+	{GET, "/1/installations/:objectId"},
+	{PUT, "/1/installations/:objectId"},
+	{GET, "/1/installations"},
+	{DELETE, "/1/installations/:objectId"},
+
+	// Cloud Functions
+	{POST, "/1/functions"},
+}
+
+var gplusAPI = []benchRoute{
+	// People
+	{GET, "/people/:userId"},
+	{GET, "/people"},
+	{GET, "/activities/:activityId/people/:collection"},
+	{GET, "/people/:userId/people/:collection"},
+	{GET, "/people/:userId/openIdConnect"},
+
+	// Activities
+	{GET, "/people/:userId/activities/:collection"},
+	{GET, "/activities/:activityId"},
+	{GET, "/activities"},
+
+	// Comments
+	{GET, "/activities/:activityId/comments"},
+	{GET, "/comments/:commentId"},
+
+	// Moments
+	{POST, "/people/:userId/moments/:collection"},
+	{GET, "/people/:userId/moments/:collection"},
+	{DELETE, "/moments/:id"},
+}
+
+func loadBenchRouter(routes []benchRoute) *NodeRouter {
+	router := NewRouter()
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	for _, r := range routes {
+		router.AddRoute(r.method, r.path, handler)
+	}
+	return router
+}
+
+// benchPath fills params of pattern with sample values.
+func benchPath(pattern string) string {
+	segments := strings.Split(pattern, "/")
+	for i, s := range segments {
+		if s != "" && (s[0] == ':' || s[0] == '*') {
+			segments[i] = "hodor"
+		}
+	}
+	return strings.Join(segments, "/")
+}
+
+func benchRequests(b *testing.B, router http.Handler, reqs []*http.Request) {
+	w := &nopResponseWriter{header: http.Header{}}
+	for _, req := range reqs {
+		rw := httptest.NewRecorder()
+		router.ServeHTTP(rw, req)
+		if rw.Code != http.StatusOK {
+			b.Fatalf("%s %s code not match. exp: 200, got: %d", req.Method, req.URL.Path, rw.Code)
+		}
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for _, req := range reqs {
+			router.ServeHTTP(w, req)
+		}
+	}
+}
+
+func benchAPI(b *testing.B, routes []benchRoute, method Method, paths ...string) {
+	router := loadBenchRouter(routes)
+	reqs := make([]*http.Request, len(paths))
+	for i, path := range paths {
+		reqs[i] = httptest.NewRequest(method.String(), path, nil)
+	}
+	benchRequests(b, router, reqs)
+}
+
+func benchAPIAll(b *testing.B, routes []benchRoute) {
+	router := loadBenchRouter(routes)
+	reqs := make([]*http.Request, len(routes))
+	for i, r := range routes {
+		reqs[i] = httptest.NewRequest(r.method.String(), benchPath(r.path), nil)
+	}
+	benchRequests(b, router, reqs)
+}
+
+func BenchmarkGithubStatic(b *testing.B) {
+	benchAPI(b, githubAPI, GET, "/user/repos")
+}
+
+func BenchmarkGithubParam(b *testing.B) {
+	benchAPI(b, githubAPI, GET, "/repos/julienschmidt/httprouter/stargazers")
+}
+
+func BenchmarkGithubAll(b *testing.B) {
+	benchAPIAll(b, githubAPI)
+}
+
+func BenchmarkParseStatic(b *testing.B) {
+	benchAPI(b, parseAPI, GET, "/1/users")
+}
+
+func BenchmarkParseParam(b *testing.B) {
+	benchAPI(b, parseAPI, GET, "/1/classes/go")
+}
+
+func BenchmarkParse2Params(b *testing.B) {
+	benchAPI(b, parseAPI, GET, "/1/classes/go/123456789")
+}
+
+func BenchmarkParseAll(b *testing.B) {
+	benchAPIAll(b, parseAPI)
+}
+
+func BenchmarkGPlusStatic(b *testing.B) {
+	benchAPI(b, gplusAPI, GET, "/people")
+}
+
+func BenchmarkGPlusParam(b *testing.B) {
+	benchAPI(b, gplusAPI, GET, "/people/118051310819094153327")
+}
+
+func BenchmarkGPlus2Params(b *testing.B) {
+	benchAPI(b, gplusAPI, GET, "/people/118051310819094153327/activities/123456789")
+}
+
+func BenchmarkGPlusAll(b *testing.B) {
+	benchAPIAll(b, gplusAPI)
+}
@@ -0,0 +1,337 @@
+/*
+ * Copyright 2016 Xuyuan Pang
+ * Author: Xuyuan Pang
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package hodor
+
+import (
+	"bufio"
+	"compress/flate"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// CompressOptions configures CompressFilter.
+type CompressOptions struct {
+	// Level is the compression level, from gzip.BestSpeed to
+	// gzip.BestCompression, or gzip.DefaultCompression if zero.
+	Level int
+	// MinSize is the minimum size of bodies to compress, 1024 if zero.
+	// Bodies are buffered until they reach it, unless flushed.
+	MinSize int
+	// ExcludedContentTypes are media types, or ranges like "image/*", of
+	// responses not compressed, DefaultExcludedContentTypes if nil.
+	ExcludedContentTypes []string
+}
+
+// DefaultExcludedContentTypes are media types already compressed.
+var DefaultExcludedContentTypes = []string{
+	"image/*",
+	"audio/*",
+	"video/*",
+	"font/woff",
+	"font/woff2",
+	"application/gzip",
+	"application/x-gzip",
+	"application/zip",
+	"application/x-bzip2",
+	"application/x-xz",
+	"application/x-7z-compressed",
+	"application/x-rar-compressed",
+	"application/zstd",
+}
+
+// CompressedSizer is implemented by ResponseWriters passed to handlers by
+// CompressFilter. Their Size returns the size of the body before compression,
+// while CompressedSize returns the size of the body sent after compression,
+// complete once the filter returns. Filters outside CompressFilter get the
+// compressed size by Size of their own ResponseWriter.
+type CompressedSizer interface {
+	CompressedSize() int
+}
+
+// compressor is implemented by gzip.Writer and flate.Writer.
+type compressor interface {
+	io.WriteCloser
+	Flush() error
+	Reset(io.Writer)
+}
+
+type compression struct {
+	minSize  int
+	excluded []string
+	pools    map[string]*sync.Pool
+}
+
+// CompressFilter new filter compressing responses by gzip or deflate, as
+// negotiated by the Accept-Encoding header. It panics if the level is
+// invalid.
+func CompressFilter(opts CompressOptions) FilterFunc {
+	level := opts.Level
+	if level == 0 {
+		level = gzip.DefaultCompression
+	}
+	if _, err := gzip.NewWriterLevel(nil, level); err != nil {
+		panic(err)
+	}
+	c := &compression{
+		minSize:  opts.MinSize,
+		excluded: opts.ExcludedContentTypes,
+		pools: map[string]*sync.Pool{
+			"gzip": {New: func() interface{} {
+				w, _ := gzip.NewWriterLevel(nil, level)
+				return w
+			}},
+			"deflate": {New: func() interface{} {
+				w, _ := flate.NewWriter(nil, level)
+				return w
+			}},
+		},
+	}
+	if c.minSize == 0 {
+		c.minSize = 1024
+	}
+	if c.excluded == nil {
+		c.excluded = DefaultExcludedContentTypes
+	}
+	c.excluded = lowerAll(c.excluded)
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(
+			func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Add("Vary", "Accept-Encoding")
+				encoding := negotiateEncoding(r.Header.Get("Accept-Encoding"))
+				if encoding == "" || Method(r.Method) == HEAD {
+					next.ServeHTTP(w, r)
+					return
+				}
+				rw, ok := w.(ResponseWriter)
+				if !ok {
+					rw = NewResponseWriter(w)
+				}
+				cw := &compressResponseWriter{ResponseWriter: rw, c: c, encoding: encoding}
+				defer cw.finish()
+				next.ServeHTTP(cw, r)
+			})
+	}
+}
+
+// negotiateEncoding returns the encoding of gzip and deflate with the
+// highest q-value in the Accept-Encoding header, preferring gzip, or an
+// empty string if neither is acceptable.
+func negotiateEncoding(accept string) string {
+	gz, df, star := -1.0, -1.0, -1.0
+	for _, part := range strings.Split(accept, ",") {
+		coding, params, err := mime.ParseMediaType(part)
+		if err != nil {
+			continue
+		}
+		q := 1.0
+		if v, ok := params["q"]; ok {
+			if q, err = strconv.ParseFloat(v, 64); err != nil {
+				continue
+			}
+		}
+		switch coding {
+		case "gzip", "x-gzip":
+			gz = q
+		case "deflate":
+			df = q
+		case "*":
+			star = q
+		}
+	}
+	if gz < 0 {
+		gz = star
+	}
+	if df < 0 {
+		df = star
+	}
+	switch {
+	case gz > 0 && gz >= df:
+		return "gzip"
+	case df > 0:
+		return "deflate"
+	}
+	return ""
+}
+
+// compressResponseWriter compresses the body written by handlers. Headers
+// are delayed until the body reaches the minimum size, or the handler
+// finishes or flushes, to decide whether to compress.
+type compressResponseWriter struct {
+	ResponseWriter
+	c        *compression
+	encoding string
+	status   int
+	size     int
+	buf      []byte
+	// decided reports whether headers are written, and cw is set if the
+	// body is compressed then.
+	decided bool
+	cw      compressor
+}
+
+func (w *compressResponseWriter) WriteHeader(s int) {
+	if w.status == 0 {
+		w.status = s
+	}
+}
+
+func (w *compressResponseWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.WriteHeader(http.StatusOK)
+	}
+	w.size += len(b)
+	if !w.decided {
+		if len(w.buf)+len(b) < w.c.minSize {
+			w.buf = append(w.buf, b...)
+			return len(b), nil
+		}
+		if err := w.decide(true, b); err != nil {
+			return 0, err
+		}
+	}
+	if w.cw != nil {
+		return w.cw.Write(b)
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *compressResponseWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+func (w *compressResponseWriter) Status() int {
+	return w.status
+}
+
+// Size returns the size of the body written by handlers, before
+// compression. The ResponseWriter wrapped reports the compressed size.
+func (w *compressResponseWriter) Size() int {
+	return w.size
+}
+
+// CompressedSize returns the size of the body sent, after compression.
+func (w *compressResponseWriter) CompressedSize() int {
+	return w.ResponseWriter.Size()
+}
+
+func (w *compressResponseWriter) Written() bool {
+	return w.status != 0
+}
+
+func (w *compressResponseWriter) Flush() {
+	if !w.decided {
+		if w.status == 0 {
+			w.WriteHeader(http.StatusOK)
+		}
+		w.decide(true, nil)
+	}
+	if w.cw != nil {
+		w.cw.Flush()
+	}
+	w.ResponseWriter.Flush()
+}
+
+func (w *compressResponseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hijacker, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, fmt.Errorf("the ResponseWriter doesn't support the Hijacker interface")
+	}
+	w.decided = true
+	return hijacker.Hijack()
+}
+
+func (w *compressResponseWriter) CloseNotify() <-chan bool {
+	return w.ResponseWriter.(http.CloseNotifier).CloseNotify()
+}
+
+// decide writes the delayed headers, compressing the body if compress is
+// true and the response is compressible, and then the buffered body. The
+// Content-Type is detected from the buffered body, or next if nothing is
+// buffered, if not set.
+func (w *compressResponseWriter) decide(compress bool, next []byte) error {
+	w.decided = true
+	h := w.Header()
+	if h.Get("Content-Type") == "" {
+		sniff := w.buf
+		if len(sniff) == 0 {
+			sniff = next
+		}
+		if len(sniff) > 0 {
+			h.Set("Content-Type", http.DetectContentType(sniff))
+		}
+	}
+	if compress && w.compressible() {
+		h.Del("Content-Length")
+		h.Set("Content-Encoding", w.encoding)
+		w.cw = w.c.pools[w.encoding].Get().(compressor)
+		w.cw.Reset(w.ResponseWriter)
+	}
+	w.ResponseWriter.WriteHeader(w.status)
+	buf := w.buf
+	w.buf = nil
+	if len(buf) == 0 {
+		return nil
+	}
+	if w.cw != nil {
+		_, err := w.cw.Write(buf)
+		return err
+	}
+	_, err := w.ResponseWriter.Write(buf)
+	return err
+}
+
+func (w *compressResponseWriter) compressible() bool {
+	h := w.Header()
+	if !bodyAllowed(w.status) || h.Get("Content-Encoding") != "" {
+		return false
+	}
+	mt, _, err := mime.ParseMediaType(h.Get("Content-Type"))
+	if err != nil {
+		return true
+	}
+	for _, mr := range w.c.excluded {
+		if matchMediaRange(mr, mt) {
+			return false
+		}
+	}
+	return true
+}
+
+// finish writes the rest of the response after the handler returns.
+func (w *compressResponseWriter) finish() {
+	if !w.decided {
+		if w.status == 0 {
+			return
+		}
+		w.decide(false, nil)
+	}
+	if w.cw != nil {
+		w.cw.Close()
+		w.cw.Reset(nil)
+		w.c.pools[w.encoding].Put(w.cw)
+		w.cw = nil
+	}
+}
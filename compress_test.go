@@ -0,0 +1,146 @@
+package hodor
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestNegotiateEncoding(t *testing.T) {
+	cases := []struct {
+		accept   string
+		encoding string
+	}{
+		{"", ""},
+		{"gzip", "gzip"},
+		{"deflate", "deflate"},
+		{"gzip, deflate", "gzip"},
+		{"gzip;q=0.5, deflate", "deflate"},
+		{"gzip;q=0, deflate;q=0", ""},
+		{"br, *;q=0.1", "gzip"},
+		{"*;q=0.5, gzip;q=0", "deflate"},
+		{"identity", ""},
+	}
+	for _, c := range cases {
+		if got, exp := negotiateEncoding(c.accept), c.encoding; got != exp {
+			t.Errorf("encoding of %q not match. exp: %q, got: %q", c.accept, exp, got)
+		}
+	}
+}
+
+func TestCompressFilter(t *testing.T) {
+	large := strings.Repeat(`{"hodor": "hodor"}`, 100)
+	var inner ResponseWriter
+	router := NewRouter()
+	route := BuildRoute(router)
+	route.Get().Pattern("/large").HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Content-Length", "1800")
+		io.WriteString(w, large)
+		inner = w.(ResponseWriter)
+	})
+	route.Get().Pattern("/small").HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "hodor")
+	})
+	route.Get().Pattern("/image").HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "image/png")
+		io.WriteString(w, large)
+	})
+	route.Get().Pattern("/stream").HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusAccepted)
+		io.WriteString(w, "hodor ")
+		w.(http.Flusher).Flush()
+		io.WriteString(w, "hodor")
+	})
+	route.Get().Pattern("/empty").HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+	h := NewHodor(router)
+	h.AddFilters(CompressFilter(CompressOptions{}))
+
+	cases := []struct {
+		path     string
+		accept   string
+		code     int
+		encoding string
+		body     string
+	}{
+		{"/large", "gzip, deflate", 200, "gzip", large},
+		{"/large", "deflate", 200, "deflate", large},
+		{"/large", "", 200, "", large},
+		{"/small", "gzip", 200, "", "hodor"},
+		{"/image", "gzip", 200, "", large},
+		{"/stream", "gzip", 202, "gzip", "hodor hodor"},
+		{"/empty", "gzip", 204, "", ""},
+	}
+	for _, c := range cases {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest("GET", c.path, nil)
+		req.Header.Set("Accept-Encoding", c.accept)
+		h.ServeHTTP(w, req)
+		if got, exp := w.Code, c.code; got != exp {
+			t.Errorf("GET %s code not match. exp: %d, got: %d", c.path, exp, got)
+		}
+		if got, exp := w.Header().Get("Content-Encoding"), c.encoding; got != exp {
+			t.Errorf("GET %s encoding not match. exp: %q, got: %q", c.path, exp, got)
+		}
+		if got, exp := w.Header().Get("Vary"), "Accept-Encoding"; got != exp {
+			t.Errorf("GET %s Vary not match. exp: %q, got: %q", c.path, exp, got)
+		}
+		var body io.Reader = w.Body
+		switch c.encoding {
+		case "gzip":
+			if w.Header().Get("Content-Length") != "" {
+				t.Errorf("GET %s Content-Length should be removed", c.path)
+			}
+			zr, err := gzip.NewReader(w.Body)
+			if err != nil {
+				t.Fatal(err)
+			}
+			body = zr
+		case "deflate":
+			body = flate.NewReader(w.Body)
+		}
+		b, err := ioutil.ReadAll(body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, exp := string(b), c.body; got != exp {
+			t.Errorf("GET %s body not match. exp: %d bytes, got: %d bytes", c.path, len(exp), len(got))
+		}
+	}
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest("GET", "/large", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	h.ServeHTTP(w, req)
+	if got, exp := inner.Size(), len(large); got != exp {
+		t.Errorf("uncompressed size not match. exp: %d, got: %d", exp, got)
+	}
+	if got, exp := inner.(CompressedSizer).CompressedSize(), w.Body.Len(); got != exp {
+		t.Errorf("compressed size not match. exp: %d, got: %d", exp, got)
+	}
+	if got, exp := inner.Status(), 200; got != exp {
+		t.Errorf("status not match. exp: %d, got: %d", exp, got)
+	}
+}
+
+func BenchmarkCompressFilter(b *testing.B) {
+	body := bytes.Repeat([]byte(`{"hodor": "hodor"}`), 1000)
+	handler := CompressFilter(CompressOptions{}).Do(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(body)
+	}))
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		handler.ServeHTTP(NewResponseWriter(&nopResponseWriter{header: http.Header{}}), req)
+	}
+}
@@ -0,0 +1,220 @@
+/*
+ * Copyright 2016 Xuyuan Pang
+ * Author: Xuyuan Pang
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package hodor
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CORSOptions configures CORSFilter.
+type CORSOptions struct {
+	// AllowedOrigins are origins allowed to make cross-origin requests, like
+	// "https://example.com", "https://*.example.com" for any subdomain, or
+	// "*" for any origin.
+	AllowedOrigins []string
+	// AllowOriginFunc reports whether origin is allowed, in addition to
+	// AllowedOrigins.
+	AllowOriginFunc func(origin string) bool
+	// AllowedMethods are methods allowed in preflight requests. If empty,
+	// methods of routes matching the path are allowed if Router is set, or
+	// else the simple methods GET, HEAD and POST.
+	AllowedMethods []Method
+	// Router lists methods of routes matching the path of preflight
+	// requests, usually the Hodor or NodeRouter the filter is added to.
+	Router MethodLister
+	// AllowedHeaders are request headers allowed in preflight requests, or
+	// "*" for any header.
+	AllowedHeaders []string
+	// ExposedHeaders are response headers exposed to clients.
+	ExposedHeaders []string
+	// AllowCredentials allows requests with cookies or HTTP authentication.
+	// It can't be used with the "*" origin.
+	AllowCredentials bool
+	// MaxAge is how long results of preflight requests can be cached, not
+	// sent if zero.
+	MaxAge time.Duration
+}
+
+// cors holds CORSOptions normalized for matching.
+type cors struct {
+	CORSOptions
+	anyOrigin bool
+	origins   []string
+	// wildcards are origins like "https://*.example.com" split around "*".
+	wildcards [][2]string
+	anyHeader bool
+	headers   map[string]bool
+	methods   string
+	exposed   string
+	maxAge    string
+}
+
+var simpleMethods = []Method{GET, HEAD, POST}
+
+// CORSFilter new filter handling cross-origin requests. Preflight requests
+// are replied without calling next, even if there is no OPTIONS route. It
+// panics if credentials are allowed for any origin, which would let any site
+// make requests with the cookies of users.
+func CORSFilter(opts CORSOptions) FilterFunc {
+	c := &cors{CORSOptions: opts, headers: map[string]bool{}}
+	for _, origin := range opts.AllowedOrigins {
+		origin = strings.ToLower(origin)
+		switch i := strings.IndexByte(origin, '*'); {
+		case origin == "*":
+			if opts.AllowCredentials {
+				panic("hodor: CORS credentials can't be allowed for any origin")
+			}
+			c.anyOrigin = true
+		case i != -1:
+			c.wildcards = append(c.wildcards, [2]string{origin[:i], origin[i+1:]})
+		default:
+			c.origins = append(c.origins, origin)
+		}
+	}
+	for _, header := range opts.AllowedHeaders {
+		if header == "*" {
+			c.anyHeader = true
+			continue
+		}
+		c.headers[http.CanonicalHeaderKey(header)] = true
+	}
+	if len(opts.AllowedMethods) > 0 {
+		c.methods = joinMethods(opts.AllowedMethods)
+	}
+	c.exposed = strings.Join(opts.ExposedHeaders, ", ")
+	if opts.MaxAge > 0 {
+		c.maxAge = strconv.Itoa(int(opts.MaxAge / time.Second))
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(
+			func(w http.ResponseWriter, r *http.Request) {
+				if Method(r.Method) == OPTIONS && r.Header.Get("Access-Control-Request-Method") != "" {
+					c.preflight(w, r)
+					return
+				}
+				c.actual(w, r)
+				next.ServeHTTP(w, r)
+			})
+	}
+}
+
+func (c *cors) allowOrigin(origin string) bool {
+	if origin == "" {
+		return false
+	}
+	if c.anyOrigin {
+		return true
+	}
+	lower := strings.ToLower(origin)
+	for _, o := range c.origins {
+		if o == lower {
+			return true
+		}
+	}
+	for _, w := range c.wildcards {
+		if len(lower) > len(w[0])+len(w[1]) &&
+			strings.HasPrefix(lower, w[0]) && strings.HasSuffix(lower, w[1]) {
+			return true
+		}
+	}
+	return c.AllowOriginFunc != nil && c.AllowOriginFunc(origin)
+}
+
+// setOrigin sets headers common to preflight and actual requests.
+func (c *cors) setOrigin(h http.Header, origin string) {
+	if c.anyOrigin {
+		h.Set("Access-Control-Allow-Origin", "*")
+	} else {
+		h.Set("Access-Control-Allow-Origin", origin)
+	}
+	if c.AllowCredentials {
+		h.Set("Access-Control-Allow-Credentials", "true")
+	}
+}
+
+func (c *cors) preflight(w http.ResponseWriter, r *http.Request) {
+	h := w.Header()
+	h.Add("Vary", "Origin")
+	h.Add("Vary", "Access-Control-Request-Method")
+	h.Add("Vary", "Access-Control-Request-Headers")
+	defer w.WriteHeader(http.StatusNoContent)
+
+	origin := r.Header.Get("Origin")
+	if !c.allowOrigin(origin) {
+		return
+	}
+	methods := c.methods
+	if methods == "" {
+		allowed := simpleMethods
+		if c.Router != nil {
+			allowed = c.Router.AllowedMethods(r)
+		}
+		methods = joinMethods(allowed)
+	}
+	method := r.Header.Get("Access-Control-Request-Method")
+	if !containsToken(methods, method) {
+		return
+	}
+	headers := r.Header.Get("Access-Control-Request-Headers")
+	for _, header := range strings.Split(headers, ",") {
+		header = http.CanonicalHeaderKey(strings.TrimSpace(header))
+		if header != "" && !c.anyHeader && !c.headers[header] {
+			return
+		}
+	}
+
+	c.setOrigin(h, origin)
+	h.Set("Access-Control-Allow-Methods", methods)
+	if headers != "" {
+		h.Set("Access-Control-Allow-Headers", headers)
+	}
+	if c.maxAge != "" {
+		h.Set("Access-Control-Max-Age", c.maxAge)
+	}
+}
+
+func (c *cors) actual(w http.ResponseWriter, r *http.Request) {
+	// Responses depend on Origin unless any origin is allowed, even without
+	// it, so that caches don't serve them to requests of other origins.
+	h := w.Header()
+	if !c.anyOrigin {
+		h.Add("Vary", "Origin")
+	}
+	origin := r.Header.Get("Origin")
+	if !c.allowOrigin(origin) {
+		return
+	}
+	c.setOrigin(h, origin)
+	if c.exposed != "" {
+		h.Set("Access-Control-Expose-Headers", c.exposed)
+	}
+}
+
+// containsToken reports whether the comma separated list has token.
+func containsToken(list, token string) bool {
+	for _, s := range strings.Split(list, ",") {
+		if strings.TrimSpace(s) == token {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,140 @@
+package hodor
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCORSFilter(t *testing.T) {
+	h := NewHodor(NewRouter())
+	h.Route().Get().Pattern("/users/:id").Handler(defaultHandler)
+	h.Route().Put().Pattern("/users/:id").Handler(defaultHandler)
+	h.AddFilters(CORSFilter(CORSOptions{
+		AllowedOrigins:   []string{"https://example.com", "https://*.acme.io"},
+		AllowOriginFunc:  func(origin string) bool { return strings.HasSuffix(origin, ".test") },
+		Router:           h,
+		AllowedHeaders:   []string{"Content-Type", "X-Token"},
+		ExposedHeaders:   []string{"X-Total"},
+		AllowCredentials: true,
+		MaxAge:           10 * time.Minute,
+	}))
+
+	cases := []struct {
+		method  Method
+		path    string
+		header  map[string]string
+		code    int
+		expects map[string]string
+	}{
+		// preflight requests.
+		{OPTIONS, "/users/42", map[string]string{
+			"Origin":                         "https://example.com",
+			"Access-Control-Request-Method":  "PUT",
+			"Access-Control-Request-Headers": "content-type, x-token",
+		}, 204, map[string]string{
+			"Access-Control-Allow-Origin":      "https://example.com",
+			"Access-Control-Allow-Methods":     "GET, HEAD, PUT",
+			"Access-Control-Allow-Headers":     "content-type, x-token",
+			"Access-Control-Allow-Credentials": "true",
+			"Access-Control-Max-Age":           "600",
+		}},
+		{OPTIONS, "/users/42", map[string]string{
+			"Origin":                        "https://api.acme.io",
+			"Access-Control-Request-Method": "GET",
+		}, 204, map[string]string{
+			"Access-Control-Allow-Origin": "https://api.acme.io",
+		}},
+		{OPTIONS, "/users/42", map[string]string{
+			"Origin":                        "https://acme.io",
+			"Access-Control-Request-Method": "GET",
+		}, 204, map[string]string{
+			"Access-Control-Allow-Origin": "",
+		}},
+		{OPTIONS, "/users/42", map[string]string{
+			"Origin":                        "http://dev.test",
+			"Access-Control-Request-Method": "DELETE",
+		}, 204, map[string]string{
+			"Access-Control-Allow-Origin":  "",
+			"Access-Control-Allow-Methods": "",
+		}},
+		{OPTIONS, "/users/42", map[string]string{
+			"Origin":                         "https://example.com",
+			"Access-Control-Request-Method":  "GET",
+			"Access-Control-Request-Headers": "X-Other",
+		}, 204, map[string]string{
+			"Access-Control-Allow-Origin": "",
+		}},
+		// actual requests.
+		{GET, "/users/42", map[string]string{"Origin": "http://dev.test"}, 200, map[string]string{
+			"Access-Control-Allow-Origin":      "http://dev.test",
+			"Access-Control-Allow-Credentials": "true",
+			"Access-Control-Expose-Headers":    "X-Total",
+			"Vary":                             "Origin",
+		}},
+		{GET, "/users/42", map[string]string{"Origin": "https://evil.com"}, 200, map[string]string{
+			"Access-Control-Allow-Origin": "",
+			"Vary":                        "Origin",
+		}},
+		{GET, "/users/42", nil, 200, map[string]string{
+			"Access-Control-Allow-Origin": "",
+			"Vary":                        "Origin",
+		}},
+		// OPTIONS without preflight headers goes to the router.
+		{OPTIONS, "/users/42", map[string]string{"Origin": "https://example.com"}, 405, nil},
+	}
+	for _, c := range cases {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(c.method.String(), c.path, nil)
+		for k, v := range c.header {
+			req.Header.Set(k, v)
+		}
+		h.ServeHTTP(w, req)
+		if got, exp := w.Code, c.code; got != exp {
+			t.Errorf("%s %s %v code not match. exp: %d, got: %d", c.method, c.path, c.header, exp, got)
+		}
+		for k, exp := range c.expects {
+			if got := w.Header().Get(k); got != exp {
+				t.Errorf("%s %s %v header %s not match. exp: %q, got: %q", c.method, c.path, c.header, k, exp, got)
+			}
+		}
+	}
+}
+
+func TestCORSFilterAnyOrigin(t *testing.T) {
+	filter := CORSFilter(CORSOptions{AllowedOrigins: []string{"*"}, AllowedHeaders: []string{"*"}})
+	handler := filter.Do(defaultHandler)
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest("OPTIONS", "/", nil)
+	req.Header.Set("Origin", "https://example.com")
+	req.Header.Set("Access-Control-Request-Method", "POST")
+	req.Header.Set("Access-Control-Request-Headers", "X-Anything")
+	handler.ServeHTTP(w, req)
+	expects := map[string]string{
+		"Access-Control-Allow-Origin":  "*",
+		"Access-Control-Allow-Methods": "GET, HEAD, POST",
+		"Access-Control-Allow-Headers": "X-Anything",
+	}
+	for k, exp := range expects {
+		if got := w.Header().Get(k); got != exp {
+			t.Errorf("header %s not match. exp: %q, got: %q", k, exp, got)
+		}
+	}
+
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+	if vary := w.Header().Get("Vary"); vary != "" {
+		t.Errorf("response for any origin should not vary, got: %q", vary)
+	}
+}
+
+func TestCORSFilterAnyOriginCredentials(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("credentials allowed for any origin should be rejected")
+		}
+	}()
+	CORSFilter(CORSOptions{AllowedOrigins: []string{"*"}, AllowCredentials: true})
+}
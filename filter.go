@@ -20,6 +20,7 @@ package hodor
 import (
 	"net/http"
 	"runtime"
+	"sync/atomic"
 	"time"
 )
 
@@ -64,31 +65,74 @@ func (f LogFunc) Printf(format string, args ...interface{}) {
 	f(format, args...)
 }
 
-// LogFilter new filter
+// LogFilter new filter logging requests and their responses. Both lines are
+// prefixed with the request ID, if it's saved by RequestIDFilter. If that's
+// added after LogFilter, the request line is logged once the request ID is
+// recorded, or once NodeRouter serves the request without it. See
+// AccessLogFilter for structured logs.
 func LogFilter(l Logger) FilterFunc {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(
 			func(w http.ResponseWriter, r *http.Request) {
-				addr := r.Header.Get("X-Real-IP")
-				if addr == "" {
-					addr = r.Header.Get("X-Forwarded-For")
-					if addr == "" {
-						addr = r.RemoteAddr
-					}
-				}
-				path := r.URL.Path
-				if r.URL.RawQuery != "" {
-					path += "?" + r.URL.RawQuery
-				}
 				start := time.Now()
-				l.Printf("%s %s %s", r.Method, path, addr)
-				next.ServeHTTP(w, r)
+				id := RequestIDOfReq(r)
+				if id != "" || atomic.LoadInt32(&requestIDFilters) == 0 {
+					logRequest(l, r, id)
+					next.ServeHTTP(w, r)
+				} else {
+					id = serveWaitingRequestID(l, next, w, r)
+				}
 				status := w.(ResponseWriter).Status()
-				l.Printf("%d %s %s", status, http.StatusText(status), time.Since(start))
+				l.Printf("%s%d %s %s", idPrefix(id), status, http.StatusText(status), time.Since(start))
 			})
 	}
 }
 
+// serveWaitingRequestID serves r by next, logging the request line once
+// RequestIDFilter added after LogFilter records the request ID, and returns
+// the request ID.
+func serveWaitingRequestID(l Logger, next http.Handler, w http.ResponseWriter, r *http.Request) string {
+	logged := false
+	ctx, rec := WithRequestRecorder(r.Context())
+	rec.waitRequestID(func(id string) {
+		logged = true
+		logRequest(l, r, id)
+	})
+	if ctx != r.Context() {
+		next.ServeHTTP(w, r.WithContext(ctx))
+	} else {
+		next.ServeHTTP(w, r)
+	}
+	if !logged {
+		logRequest(l, r, rec.RequestID())
+	}
+	return rec.RequestID()
+}
+
+// logRequest logs the request line of r.
+func logRequest(l Logger, r *http.Request, id string) {
+	addr := r.Header.Get("X-Real-IP")
+	if addr == "" {
+		addr = r.Header.Get("X-Forwarded-For")
+		if addr == "" {
+			addr = r.RemoteAddr
+		}
+	}
+	path := r.URL.Path
+	if r.URL.RawQuery != "" {
+		path += "?" + r.URL.RawQuery
+	}
+	l.Printf("%s%s %s %s", idPrefix(id), r.Method, path, addr)
+}
+
+// idPrefix returns the prefix of log lines of the request ID.
+func idPrefix(id string) string {
+	if id == "" {
+		return ""
+	}
+	return "[" + id + "] "
+}
+
 // RecoveryFilter new filter
 func RecoveryFilter(l Logger) FilterFunc {
 	return func(next http.Handler) http.Handler {
@@ -18,15 +18,28 @@
 package hodor
 
 import (
+	"errors"
 	"log"
 	"net/http"
 	"os"
+	"sync"
+	"sync/atomic"
 )
 
 // Hodor struct
 type Hodor struct {
+	mu     sync.Mutex
 	router Router
 	filter Filter
+	// chain is the router wrapped by filter, composed whenever filters
+	// change, so that filters are applied once rather than per request.
+	chain atomic.Value // chain
+}
+
+// chain wraps the composed handler, as atomic.Value requires values of the
+// same concrete type.
+type chain struct {
+	http.Handler
 }
 
 // NewHodor creates new Hodor with Router
@@ -35,6 +48,7 @@ func NewHodor(router Router) *Hodor {
 		router: router,
 		filter: emptyFilter,
 	}
+	h.chain.Store(chain{router})
 	return h
 }
 
@@ -50,7 +64,7 @@ func Default() *Hodor {
 }
 
 func (h *Hodor) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-	h.filter.Do(h.router).ServeHTTP(NewResponseWriter(w), r)
+	h.chain.Load().(chain).ServeHTTP(NewResponseWriter(w), r)
 }
 
 // Route returns root route
@@ -63,12 +77,48 @@ func (h *Hodor) Run(addr string) error {
 	return http.ListenAndServe(addr, h)
 }
 
-// AddFilters appends filters to current filter
+// AddFilters appends filters to current filter. Filters are applied to the
+// router once here, and requests in flight keep the previous chain, so it's
+// safe to call it while serving requests.
 func (h *Hodor) AddFilters(filters ...Filter) {
-	h.filter = MergeFilters(h.filter, MergeFilters(filters...))
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.setFilter(MergeFilters(h.filter, MergeFilters(filters...)))
 }
 
-// SetFilters replace current filter with the merged filters
+// SetFilters replace current filter with the merged filters, see AddFilters.
 func (h *Hodor) SetFilters(filters ...Filter) {
-	h.filter = MergeFilters(filters...)
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.setFilter(MergeFilters(filters...))
+}
+
+func (h *Hodor) setFilter(filter Filter) {
+	h.filter = filter
+	h.chain.Store(chain{filter.Do(h.router)})
+}
+
+// URL builds the path of the named route, if the router is an URLBuilder.
+func (h *Hodor) URL(name string, pairs ...string) (string, error) {
+	if b, ok := h.router.(URLBuilder); ok {
+		return b.URL(name, pairs...)
+	}
+	return "", errors.New("hodor: router doesn't support named routes")
+}
+
+// Walk walks routes, if the router is a Walker.
+func (h *Hodor) Walk(fn func(RouteInfo) error) error {
+	if walker, ok := h.router.(Walker); ok {
+		return walker.Walk(fn)
+	}
+	return errors.New("hodor: router doesn't support walking routes")
+}
+
+// AllowedMethods lists methods of routes matching r, if the router is a
+// MethodLister.
+func (h *Hodor) AllowedMethods(r *http.Request) []Method {
+	if lister, ok := h.router.(MethodLister); ok {
+		return lister.AllowedMethods(r)
+	}
+	return nil
 }
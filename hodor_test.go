@@ -0,0 +1,71 @@
+package hodor
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+)
+
+func TestFilterChain(t *testing.T) {
+	h := NewHodor(NewRouter())
+	h.Route().Get().Pattern("/").Handler(defaultHandler)
+
+	var applied int
+	counter := FilterFunc(func(next http.Handler) http.Handler {
+		applied++
+		var served int
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			served++
+			w.Header().Set("X-Served", fmt.Sprint(served))
+			next.ServeHTTP(w, r)
+		})
+	})
+	h.SetFilters(counter)
+
+	for i := 1; i <= 3; i++ {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+		if got, exp := w.Header().Get("X-Served"), fmt.Sprint(i); got != exp {
+			t.Errorf("served count not match. exp: %s, got: %s", exp, got)
+		}
+	}
+	if applied != 1 {
+		t.Errorf("filter should be applied once, got: %d", applied)
+	}
+
+	h.AddFilters(FilterFunc(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("X-Added", "hodor")
+			next.ServeHTTP(w, r)
+		})
+	}))
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+	if got, exp := w.Header().Get("X-Added"), "hodor"; got != exp {
+		t.Errorf("added filter not applied. exp: %s, got: %s", exp, got)
+	}
+}
+
+func TestFilterChainSwap(t *testing.T) {
+	h := NewHodor(NewRouter())
+	h.Route().Get().Pattern("/").Handler(defaultHandler)
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 100; i++ {
+			h.SetFilters(emptyFilter, emptyFilter)
+		}
+	}()
+	for i := 0; i < 100; i++ {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+		if w.Code != 200 {
+			t.Errorf("GET / code not match. exp: 200, got: %d", w.Code)
+		}
+	}
+	wg.Wait()
+}
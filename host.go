@@ -0,0 +1,151 @@
+/*
+ * Copyright 2017 Xuyuan Pang
+ * Author: Xuyuan Pang
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package hodor
+
+import (
+	"errors"
+	"net"
+	"strings"
+)
+
+// hostTree holds routes of a host pattern. Host patterns are dot separated
+// labels, each of them is static text, or a param like ":tenant" matching a
+// single label. The first label may be a wildcard like "*" or "*sub" matching
+// one or more labels, e.g. "*.example.com" matches "a.b.example.com".
+type hostTree struct {
+	pattern string
+	labels  []string
+	root    *node
+}
+
+// rank orders host trees: static hosts first, then hosts with params, and
+// wildcard hosts at last.
+func (ht *hostTree) rank() int {
+	switch {
+	case strings.HasPrefix(ht.pattern, "*"):
+		return 2
+	case strings.Contains(ht.pattern, ":"):
+		return 1
+	}
+	return 0
+}
+
+// parseHost validates pattern and splits it into labels.
+func parseHost(pattern string) ([]string, error) {
+	if pattern == "" {
+		return nil, errors.New("host must not be empty")
+	}
+	labels := strings.Split(pattern, ".")
+	for i, label := range labels {
+		name := label
+		switch {
+		case label == "":
+			return nil, errors.New("host must not have empty labels")
+		case label[0] == '*':
+			if i != 0 {
+				return nil, errors.New("wildcard must be the first label of host")
+			}
+			name = label[1:]
+		case label[0] == ':':
+			name = label[1:]
+			if name == "" {
+				return nil, errors.New("host param must be named")
+			}
+		}
+		for j := 0; j < len(name); j++ {
+			if c := name[j]; !isNameChar(c) && c != '-' {
+				return nil, errors.New("invalid host label " + label)
+			}
+		}
+	}
+	return labels, nil
+}
+
+// lowerHost lowers static labels of the host pattern, leaving names of
+// params as is.
+func lowerHost(pattern string) string {
+	labels := strings.Split(pattern, ".")
+	for i, label := range labels {
+		if label != "" && label[0] != ':' && label[0] != '*' {
+			labels[i] = strings.ToLower(label)
+		}
+	}
+	return strings.Join(labels, ".")
+}
+
+// paramNames returns names of params in the host pattern.
+func (ht *hostTree) paramNames() []string {
+	var names []string
+	for _, label := range ht.labels {
+		if len(label) > 1 && (label[0] == ':' || label[0] == '*') {
+			names = append(names, label[1:])
+		}
+	}
+	return names
+}
+
+// match reports whether host matches the pattern, and adds params to ps.
+// Labels are compared from right to left.
+func (ht *hostTree) match(host string, ps *params) bool {
+	end := len(host)
+	for i := len(ht.labels) - 1; i >= 0; i-- {
+		label := ht.labels[i]
+		if label[0] == '*' {
+			if end <= 0 {
+				return false
+			}
+			if len(label) > 1 {
+				ps.add(label[1:], host[:end])
+			}
+			return true
+		}
+		if end < 0 {
+			return false
+		}
+		start := strings.LastIndexByte(host[:end], '.') + 1
+		value := host[start:end]
+		if label[0] == ':' {
+			if value == "" {
+				return false
+			}
+			ps.add(label[1:], value)
+		} else if value != label {
+			return false
+		}
+		end = start - 1
+	}
+	return end == -1
+}
+
+// canonicalHost strips the port and the trailing dot of host, and lowers it.
+func canonicalHost(host string) string {
+	if i := strings.LastIndexByte(host, ':'); i != -1 && i > strings.LastIndexByte(host, ']') {
+		if h, _, err := net.SplitHostPort(host); err == nil {
+			host = h
+		} else {
+			host = host[:i]
+		}
+	}
+	host = strings.TrimSuffix(host, ".")
+	for i := 0; i < len(host); i++ {
+		if c := host[i]; 'A' <= c && c <= 'Z' {
+			return strings.ToLower(host)
+		}
+	}
+	return host
+}
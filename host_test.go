@@ -0,0 +1,98 @@
+package hodor
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestHostRouting(t *testing.T) {
+	router := NewRouter()
+	route := BuildRoute(router)
+
+	hostHandler := func(name string) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ps := ParamListOfReq(r)
+			var kvs []string
+			for i := 0; i < ps.Len(); i++ {
+				k, v := ps.At(i)
+				kvs = append(kvs, k+"="+v)
+			}
+			fmt.Fprint(w, name, " ", strings.Join(kvs, ","))
+		})
+	}
+	route.Host("api.example.com").Get().Pattern("/users/:id").Handler(hostHandler("api"))
+	route.Host(":tenant.example.com").Get().Pattern("/users/:id").Handler(hostHandler("tenant"))
+	route.Host("*sub.example.com").Get().Pattern("/users/:id").Handler(hostHandler("wildcard"))
+	route.Host("*.Static.Example.com").Get().Pattern("/*filepath").Handler(hostHandler("static"))
+	route.Get().Pattern("/users/:id").Handler(hostHandler("default"))
+	route.Get().Pattern("/about").Handler(hostHandler("default"))
+
+	cases := []struct {
+		host string
+		path string
+		code int
+		body string
+	}{
+		{"api.example.com", "/users/42", 200, "api id=42"},
+		{"API.example.com:8080", "/users/42", 200, "api id=42"},
+		{"api.example.com.", "/users/42", 200, "api id=42"},
+		{"acme.example.com", "/users/42", 200, "tenant tenant=acme,id=42"},
+		{"a.b.example.com", "/users/42", 200, "wildcard sub=a.b,id=42"},
+		{"cdn.static.example.com", "/css/main.css", 200, "static filepath=css/main.css"},
+		// falls back to routes without host.
+		{"example.com", "/users/42", 200, "default id=42"},
+		{"localhost:8080", "/users/42", 200, "default id=42"},
+		{"[::1]:8080", "/users/42", 200, "default id=42"},
+		{"api.example.com", "/about", 200, "default "},
+		{"api.example.com", "/missing", 404, ""},
+	}
+	for _, c := range cases {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest("GET", c.path, nil)
+		req.Host = c.host
+		router.ServeHTTP(w, req)
+		if got, exp := w.Code, c.code; got != exp {
+			t.Errorf("%s%s code not match. exp: %d, got: %d", c.host, c.path, exp, got)
+			continue
+		}
+		if c.code == 200 {
+			if got, exp := w.Body.String(), c.body; got != exp {
+				t.Errorf("%s%s body not match. exp: %q, got: %q", c.host, c.path, exp, got)
+			}
+		}
+	}
+}
+
+func TestHostRouteErrors(t *testing.T) {
+	router := NewRouter()
+	router.AddRoute(GET, "/users", defaultHandler, WithHost("api.example.com"))
+
+	if err := router.TryAddRoute(GET, "/users", defaultHandler, WithHost("api.example.com")); err == nil {
+		t.Errorf("duplicated host route should be rejected")
+	}
+	if err := router.TryAddRoute(GET, "/users", defaultHandler); err != nil {
+		t.Errorf("route without host should be added, got: %v", err)
+	}
+	for _, host := range []string{"api..example.com", "api.*.com", ":.example.com", "api/v1.example.com"} {
+		if err := router.TryAddRoute(GET, "/users", defaultHandler, WithHost(host)); err == nil {
+			t.Errorf("host %s should be rejected", host)
+		}
+	}
+
+	if router.RemoveRoute(GET, "/users", WithHost("www.example.com")) {
+		t.Errorf("route of unknown host should not be removed")
+	}
+	if !router.RemoveRoute(GET, "/users", WithHost("api.example.com")) {
+		t.Errorf("route of api.example.com should be removed")
+	}
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest("GET", "/users", nil)
+	req.Host = "api.example.com"
+	router.ServeHTTP(w, req)
+	if got, exp := w.Code, 200; got != exp {
+		t.Errorf("GET api.example.com/users code not match. exp: %d, got: %d", exp, got)
+	}
+}
@@ -0,0 +1,202 @@
+/*
+ * Copyright 2016 Xuyuan Pang
+ * Author: Xuyuan Pang
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package hodor
+
+import (
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// Matcher matches requests beyond method and path. Several routes of the same
+// method and pattern may be added with different matchers, and the first one
+// whose matchers all match the request, in the order of registration, serves
+// it. A route without matchers of the same method and pattern is tried at
+// last. If none of them matches, the request falls through to other patterns
+// matching the path, like "/users/:id" for "/users/new".
+type Matcher interface {
+	Match(r *http.Request) bool
+}
+
+// MatcherFunc is a function type implemented Matcher interface.
+type MatcherFunc func(r *http.Request) bool
+
+// Match calls f(r).
+func (f MatcherFunc) Match(r *http.Request) bool {
+	return f(r)
+}
+
+// statusMatcher is a Matcher replying status if no route matches because of
+// it.
+type statusMatcher struct {
+	status int
+	match  func(r *http.Request) bool
+}
+
+func (m *statusMatcher) Match(r *http.Request) bool {
+	return m.match(r)
+}
+
+// MatchHeader matches requests with the header key of value, or with the
+// header key of any value if value is empty.
+func MatchHeader(key, value string) Matcher {
+	key = http.CanonicalHeaderKey(key)
+	return MatcherFunc(func(r *http.Request) bool {
+		values, ok := r.Header[key]
+		if !ok || value == "" {
+			return ok
+		}
+		for _, v := range values {
+			if v == value {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+// MatchQuery matches requests with the query param key of value, or with the
+// query param key of any value if value is empty.
+func MatchQuery(key, value string) Matcher {
+	return MatcherFunc(func(r *http.Request) bool {
+		values, ok := r.URL.Query()[key]
+		if !ok || value == "" {
+			return ok
+		}
+		for _, v := range values {
+			if v == value {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+// MatchAccept matches requests accepting one of media types, like
+// "application/vnd.acme.v2+json". Media ranges like "application/*" in the
+// Accept header are supported, and ranges of q=0 are refused. Requests
+// without the Accept header accept any media type. If no route matches
+// because of it, NodeRouter replies 406 Not Acceptable.
+func MatchAccept(mediaTypes ...string) Matcher {
+	mediaTypes = lowerAll(mediaTypes)
+	return &statusMatcher{
+		status: http.StatusNotAcceptable,
+		match: func(r *http.Request) bool {
+			accept := r.Header.Get("Accept")
+			if accept == "" {
+				return true
+			}
+			for _, part := range strings.Split(accept, ",") {
+				mr, params, err := mime.ParseMediaType(part)
+				if err != nil {
+					continue
+				}
+				if q, ok := params["q"]; ok {
+					if v, err := strconv.ParseFloat(q, 64); err != nil || v <= 0 {
+						continue
+					}
+				}
+				for _, mt := range mediaTypes {
+					if matchMediaRange(mr, mt) {
+						return true
+					}
+				}
+			}
+			return false
+		},
+	}
+}
+
+// MatchContentType matches requests of one of media types by the
+// Content-Type header. Media ranges like "text/*" are supported. If no route
+// matches because of it, NodeRouter replies 415 Unsupported Media Type.
+func MatchContentType(mediaTypes ...string) Matcher {
+	mediaTypes = lowerAll(mediaTypes)
+	return &statusMatcher{
+		status: http.StatusUnsupportedMediaType,
+		match: func(r *http.Request) bool {
+			mt, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+			if err != nil {
+				return false
+			}
+			for _, mr := range mediaTypes {
+				if matchMediaRange(mr, mt) {
+					return true
+				}
+			}
+			return false
+		},
+	}
+}
+
+// matchMediaRange reports whether the media type mt is in the media range mr.
+// Both of them should be in lower case.
+func matchMediaRange(mr, mt string) bool {
+	if mr == "*/*" || mr == mt {
+		return true
+	}
+	return strings.HasSuffix(mr, "/*") && strings.HasPrefix(mt, mr[:len(mr)-1])
+}
+
+func lowerAll(ss []string) []string {
+	lowered := make([]string, len(ss))
+	for i, s := range ss {
+		lowered[i] = strings.ToLower(s)
+	}
+	return lowered
+}
+
+// pickRoute returns the first route of routes matching r. If there is none,
+// it returns the status to reply, the largest one of failed matchers, which
+// is 404 for matchers without a status.
+func pickRoute(routes []*route, r *http.Request) (*route, int) {
+	status := http.StatusNotFound
+	for _, rt := range routes {
+		ok := true
+		for _, m := range rt.matchers {
+			if !m.Match(r) {
+				if sm, isStatus := m.(*statusMatcher); isStatus && sm.status > status {
+					status = sm.status
+				}
+				ok = false
+				break
+			}
+		}
+		if ok {
+			return rt, 0
+		}
+	}
+	return nil, status
+}
+
+// WithMatchers restricts a route to requests matching all of matchers, see
+// HandlerSetter.When.
+func WithMatchers(matchers ...Matcher) RouteOption {
+	return matchOption(matchers)
+}
+
+type matchOption []Matcher
+
+func (o matchOption) Do(next http.Handler) http.Handler {
+	return next
+}
+
+func (o matchOption) apply(cfg *routeConfig) {
+	cfg.matchers = append(cfg.matchers, o...)
+}
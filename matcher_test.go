@@ -0,0 +1,109 @@
+package hodor
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestMatchers(t *testing.T) {
+	router := NewRouter()
+	route := BuildRoute(router)
+
+	named := func(name string) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, name)
+		})
+	}
+	route.Get().Pattern("/items").When(MatchAccept("application/vnd.acme.v2+json")).Handler(named("v2"))
+	route.Get().Pattern("/items").When(MatchAccept("application/vnd.acme.v1+json", "application/json")).Handler(named("v1"))
+	route.Post().Pattern("/items").When(MatchContentType("application/json")).Handler(named("json"))
+	route.Post().Pattern("/items").When(MatchContentType("text/*")).Handler(named("text"))
+	route.Get().Pattern("/debug").When(MatchHeader("X-Debug", "")).Handler(named("debug"))
+	route.Get().Pattern("/search").When(MatchQuery("v", "2")).Handler(named("search2"))
+	route.Get().Pattern("/search").Handler(named("search"))
+	route.Get().Pattern("/a/b").When(MatchHeader("X-V", "2")).Handler(named("b2"))
+	route.Get().Pattern("/a/:p").Handler(named("param"))
+	route.Get().Pattern("/f/b").When(MatchAccept("application/json")).Handler(named("json"))
+	route.Get().Pattern("/f/*path").Handler(named("catch-all"))
+	route.Get().Pattern("/g/b").When(MatchAccept("application/json")).Handler(named("json"))
+	route.Post().Pattern("/g/:p").Handler(named("post"))
+
+	cases := []struct {
+		method Method
+		path   string
+		header map[string]string
+		code   int
+		body   string
+	}{
+		{GET, "/items", map[string]string{"Accept": "application/vnd.acme.v2+json"}, 200, "v2"},
+		{GET, "/items", map[string]string{"Accept": "application/vnd.acme.v1+json"}, 200, "v1"},
+		{GET, "/items", map[string]string{"Accept": "text/html, application/*;q=0.5"}, 200, "v2"},
+		{GET, "/items", map[string]string{"Accept": "application/vnd.acme.v2+json;q=0, application/json"}, 200, "v1"},
+		{GET, "/items", nil, 200, "v2"},
+		{GET, "/items", map[string]string{"Accept": "text/html"}, 406, ""},
+		{HEAD, "/items", map[string]string{"Accept": "text/html"}, 406, ""},
+		{POST, "/items", map[string]string{"Content-Type": "application/json; charset=utf-8"}, 200, "json"},
+		{POST, "/items", map[string]string{"Content-Type": "Text/Plain"}, 200, "text"},
+		{POST, "/items", map[string]string{"Content-Type": "image/png"}, 415, ""},
+		{POST, "/items", nil, 415, ""},
+		{PUT, "/items", nil, 405, ""},
+		{GET, "/debug", map[string]string{"X-Debug": "1"}, 200, "debug"},
+		{GET, "/debug", nil, 404, ""},
+		{GET, "/search?v=2", nil, 200, "search2"},
+		{GET, "/search?v=1", nil, 200, "search"},
+		{GET, "/a/b", map[string]string{"X-V": "2"}, 200, "b2"},
+		{GET, "/a/b", nil, 200, "param"},
+		{GET, "/f/b", map[string]string{"Accept": "text/html"}, 200, "catch-all"},
+		{GET, "/g/b", map[string]string{"Accept": "text/html"}, 406, ""},
+	}
+	for _, c := range cases {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(c.method.String(), c.path, nil)
+		for k, v := range c.header {
+			req.Header.Set(k, v)
+		}
+		router.ServeHTTP(w, req)
+		if got, exp := w.Code, c.code; got != exp {
+			t.Errorf("%s %s %v code not match. exp: %d, got: %d", c.method, c.path, c.header, exp, got)
+			continue
+		}
+		if c.code == 200 && c.method != HEAD {
+			if got, exp := w.Body.String(), c.body; got != exp {
+				t.Errorf("%s %s %v body not match. exp: %q, got: %q", c.method, c.path, c.header, exp, got)
+			}
+		}
+	}
+}
+
+func TestMatchersRegistration(t *testing.T) {
+	router := NewRouter()
+	router.AddRoute(GET, "/items", defaultHandler, WithMatchers(MatchHeader("X-Version", "2")))
+	router.AddRoute(GET, "/items", defaultHandler)
+
+	if err := router.TryAddRoute(GET, "/items", defaultHandler); err == nil {
+		t.Errorf("second route without matchers should be rejected")
+	}
+	if err := router.TryAddRoute(GET, "/items", defaultHandler, WithMatchers(MatchHeader("X-Version", "3"))); err != nil {
+		t.Errorf("route with matchers should be added, got: %v", err)
+	}
+
+	var count int
+	router.Walk(func(info RouteInfo) error {
+		count++
+		return nil
+	})
+	if count != 3 {
+		t.Errorf("routes count not match. exp: 3, got: %d", count)
+	}
+
+	if !router.RemoveRoute(GET, "/items") {
+		t.Errorf("routes of GET /items should be removed")
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/items", nil))
+	if got, exp := w.Code, 404; got != exp {
+		t.Errorf("GET /items code not match. exp: %d, got: %d", exp, got)
+	}
+}
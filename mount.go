@@ -0,0 +1,130 @@
+/*
+ * Copyright 2016 Xuyuan Pang
+ * Author: Xuyuan Pang
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package hodor
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+const mountKey paramsKeyType = "HodorMount"
+
+// mountInfo is saved in the context of requests passed to mounted handlers.
+type mountInfo struct {
+	original *url.URL
+	prefix   string
+}
+
+// OriginalURLOfReq returns the URL of the request before any mounted prefix
+// was stripped, see Route.Mount. It's r.URL if the request isn't passed to a
+// mounted handler.
+func OriginalURLOfReq(r *http.Request) *url.URL {
+	if info, ok := r.Context().Value(mountKey).(*mountInfo); ok {
+		return info.original
+	}
+	return r.URL
+}
+
+// MountPrefixOfReq returns the path prefix stripped from the request by
+// mounted handlers, or an empty string if there is none.
+func MountPrefixOfReq(r *http.Request) string {
+	return MountPrefixOfCtx(r.Context())
+}
+
+// MountPrefixOfCtx returns the path prefix stripped from the request by
+// mounted handlers, see MountPrefixOfReq.
+func MountPrefixOfCtx(ctx context.Context) string {
+	if info, ok := ctx.Value(mountKey).(*mountInfo); ok {
+		return info.prefix
+	}
+	return ""
+}
+
+// mountSuffix is the catch-all pattern of the sub-paths under a prefix.
+const mountSuffix = "/*mountpath"
+
+// mountPattern returns the cleaned prefix and the catch-all pattern of the
+// sub-paths under it.
+func mountPattern(prefix string) (string, string) {
+	prefix = strings.TrimRight(prefix, "/")
+	return prefix, prefix + mountSuffix
+}
+
+// mountHandler strips the matched prefix from requests before passing them
+// to handler. If sub is true, the route ends with a catch-all param holding
+// the rest of the path, otherwise the whole path is the prefix.
+type mountHandler struct {
+	handler http.Handler
+	sub     bool
+}
+
+func (h *mountHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	rest := ""
+	if ps := ParamListOfReq(r); h.sub && ps.Len() > 0 {
+		_, rest = ps.At(ps.Len() - 1)
+	}
+	prefix := strings.TrimSuffix(r.URL.Path[:len(r.URL.Path)-len(rest)], "/")
+
+	info := &mountInfo{original: r.URL, prefix: prefix}
+	if outer, ok := r.Context().Value(mountKey).(*mountInfo); ok {
+		info.original = outer.original
+		info.prefix = outer.prefix + prefix
+	}
+
+	if rec := requestRecorderOfCtx(r.Context()); rec != nil {
+		if rt, ok := RouteOfReq(r); ok {
+			rec.recordMount(rt.Pattern, h.sub)
+		}
+	}
+
+	u := new(url.URL)
+	*u = *r.URL
+	u.Path = "/" + rest
+	u.RawPath = stripRawPath(r.URL.RawPath, prefix)
+	r2 := r.WithContext(context.WithValue(r.Context(), mountKey, info))
+	r2.URL = u
+	h.handler.ServeHTTP(w, r2)
+}
+
+// stripRawPath strips the escaped form of prefix from rawPath. It returns
+// an empty string, letting URL.EscapedPath fall back to escaping Path, if
+// rawPath is empty or the prefix has no unambiguous escaped form in it.
+func stripRawPath(rawPath, prefix string) string {
+	if rawPath == "" {
+		return ""
+	}
+	n := strings.Count(prefix, "/")
+	i := 0
+	for ; n > 0; n-- {
+		j := strings.IndexByte(rawPath[i+1:], '/')
+		if j < 0 {
+			i = len(rawPath)
+			break
+		}
+		i += j + 1
+	}
+	if p, err := url.PathUnescape(rawPath[:i]); err != nil || p != prefix {
+		return ""
+	}
+	if rest := rawPath[i:]; rest != "" {
+		return rest
+	}
+	return "/"
+}
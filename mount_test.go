@@ -0,0 +1,69 @@
+package hodor
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestMount(t *testing.T) {
+	admin := NewRouter()
+	adminRoute := BuildRoute(admin)
+	adminRoute.Get().Pattern("/").Handler(defaultHandler)
+	adminRoute.Get().Pattern("/users/:id").Handler(defaultHandler)
+	adminRoute.Post().Pattern("/users").Handler(defaultHandler)
+
+	inspect := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "%s %s %s %s", r.URL.Path, r.URL.EscapedPath(), MountPrefixOfReq(r), OriginalURLOfReq(r).Path)
+	})
+	nested := NewRouter()
+	BuildRoute(nested).Mount("/files", inspect)
+
+	router := NewRouter()
+	route := BuildRoute(router)
+	route.Mount("/admin/", admin)
+	route.Mount("/teams/:team", nested)
+	route.Get().Pattern("/admins").Handler(defaultHandler)
+
+	cases := []struct {
+		method Method
+		path   string
+		code   int
+		body   string
+	}{
+		{GET, "/admin", 200, "/"},
+		{GET, "/admin/", 200, "/"},
+		{GET, "/admin/users/42", 200, "/users/42"},
+		{POST, "/admin/users", 200, "/users"},
+		{DELETE, "/admin/users", 405, ""},
+		{GET, "/admin/missing", 404, ""},
+		{GET, "/admins", 200, "/admins"},
+		{GET, "/teams/a/files/x%2Fy", 200, "/x/y /x%2Fy /teams/a/files /teams/a/files/x/y"},
+		{PUT, "/teams/a/files", 200, "/ / /teams/a/files /teams/a/files"},
+	}
+	for _, c := range cases {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(c.method.String(), c.path, nil))
+		if got, exp := w.Code, c.code; got != exp {
+			t.Errorf("%s %s code not match. exp: %d, got: %d", c.method, c.path, exp, got)
+			continue
+		}
+		if c.code == 200 {
+			if got, exp := w.Body.String(), c.body; got != exp {
+				t.Errorf("%s %s body not match. exp: %q, got: %q", c.method, c.path, exp, got)
+			}
+		}
+	}
+}
+
+func TestMountRoot(t *testing.T) {
+	router := NewRouter()
+	BuildRoute(router).Mount("/", defaultHandler)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("PATCH", "/a/b", nil))
+	if got, exp := w.Body.String(), "/a/b"; got != exp {
+		t.Errorf("PATCH /a/b body not match. exp: %q, got: %q", exp, got)
+	}
+}
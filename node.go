@@ -18,235 +18,720 @@
 package hodor
 
 import (
-	"context"
 	"errors"
 	"fmt"
 	"net/http"
+	"regexp"
+	"sort"
 	"strings"
 )
 
-type paramsKeyType string
-
-const paramsKey paramsKeyType = "HodorParam"
-
-func ParamsOfReq(r *http.Request, name string) (string, bool) {
-	return ParamsOfCtx(r.Context(), name)
+// route is a registered route.
+type route struct {
+	RouteInfo
+	handler  http.Handler
+	matchers []Matcher
 }
 
-func ParamsOfCtx(ctx context.Context, name string) (string, bool) {
-	if p, ok := ctx.Value(paramsKey).(Params); ok {
-		return p.Get(name)
+// newError returns a RouteError of rt, conflicting with existing if not nil.
+func (rt *route) newError(existing *route, reason string) *RouteError {
+	err := &RouteError{
+		Method:  rt.Method,
+		Pattern: rt.Pattern,
+		Reason:  reason,
 	}
-	return "", false
-}
-
-type Params interface {
-	Get(string) (string, bool)
-}
-
-type emptyParams struct{}
-
-func (p *emptyParams) Get(_ string) (string, bool) {
-	return "", false
-}
-
-var background = new(emptyParams)
-
-type valueParam struct {
-	parent Params
-	name   string
-	value  string
-}
-
-func (p *valueParam) Get(name string) (string, bool) {
-	if p.name == name {
-		return p.value, true
+	if existing != nil {
+		info := existing.RouteInfo
+		err.Conflict = &info
 	}
-	return p.parent.Get(name)
+	return err
 }
 
-func withValue(p Params, name, value string) Params {
-	return &valueParam{
-		parent: p,
-		name:   name,
-		value:  value,
-	}
-}
+type nodeKind uint8
+
+const (
+	staticNode nodeKind = iota
+	paramNode
+	catchAllNode
+)
 
 type node struct {
-	parent   *node
-	named    bool
-	empty    bool
-	pattern  string
-	name     string
-	handlers map[Method]http.Handler
-	children map[byte]*node
+	kind       nodeKind
+	empty      bool
+	pattern    string
+	name       string
+	constraint string
+	re         *regexp.Regexp
+	// handlers holds routes of each method. Routes with matchers are in the
+	// order of registration, followed by the route without matchers if any.
+	handlers map[Method][]*route
+	// indices holds the first bytes of static children, in the same order
+	// as children, which are ordered by priority, so that the most used
+	// children are found first.
+	indices  string
+	children []*node
+	// priority is the count of routes of n and its descendants.
+	priority int
+	params   []*node
+	catchAll *node
 }
 
-func newNode(parent *node, pattern string) *node {
+func newNode(pattern string) *node {
 	return &node{
-		parent:   parent,
 		pattern:  pattern,
-		named:    false,
+		kind:     staticNode,
 		empty:    true,
-		handlers: map[Method]http.Handler{},
-		children: map[byte]*node{},
+		handlers: map[Method][]*route{},
 	}
 }
 
-func (n *node) addRoute(method Method, pattern string, handler http.Handler) {
+func (n *node) addRoute(pattern string, rt *route) error {
 	if n.empty {
-		n.init(method, pattern, handler)
-		return
-	}
-	if n.named {
-		n.addNamedRoute(method, pattern, handler)
-		return
+		return n.init(pattern, rt)
 	}
 	i := longestPrefix(n.pattern, pattern)
 	if i < len(n.pattern) {
 		n.splitAt(i)
 	}
 	if i == len(pattern) {
-		n.handle(method, handler)
-		return
+		return n.handle(rt)
 	}
-	n.getChildMust(pattern[i]).addRoute(method, pattern[i:], handler)
+	return n.addChildRoute(pattern, i, rt)
 }
 
-func (n *node) init(method Method, pattern string, handler http.Handler) {
+func (n *node) init(pattern string, rt *route) error {
 	n.empty = false
 	i := longestPrefix(pattern, pattern)
-	if i == 0 {
-		n.addNamedRoute(method, pattern, handler)
-		return
-	}
+	n.pattern = pattern[:i]
 	if i == len(pattern) {
-		n.pattern = pattern
-		n.handle(method, handler)
-		return
+		return n.handle(rt)
+	}
+	return n.addChildRoute(pattern, i, rt)
+}
+
+// addChildRoute adds the rest of pattern starting at index i to a child of n.
+// The pattern must have been checked by validatePattern.
+func (n *node) addChildRoute(pattern string, i int, rt *route) error {
+	switch pattern[i] {
+	case ':':
+		name, constraint, rest := parseParam(pattern[i:])
+		child, err := n.getParamChild(name, constraint, rt)
+		if err != nil {
+			return err
+		}
+		if rest == "" {
+			return child.handle(rt)
+		}
+		return child.addChildRoute(rest, 0, rt)
+	case '*':
+		return n.addCatchAllRoute(pattern[i:], rt)
+	default:
+		child, index := n.getChildMust(pattern[i])
+		if err := child.addRoute(pattern[i:], rt); err != nil {
+			return err
+		}
+		child.priority++
+		n.sortChild(index)
+		return nil
 	}
-	n.pattern = pattern[:i]
-	n.getChildMust(pattern[i]).addRoute(method, pattern[i:], handler)
 }
 
-func (n *node) addNamedRoute(method Method, pattern string, handler http.Handler) {
-	index := strings.Index(pattern, "/")
-	var name string
-	if index == -1 {
-		name = pattern[1:]
-		n.name = name
-		n.handle(method, handler)
+func (n *node) addCatchAllRoute(pattern string, rt *route) error {
+	name := pattern[1:]
+	if n.catchAll == nil {
+		n.catchAll = newNode("")
+		n.catchAll.kind = catchAllNode
+		n.catchAll.empty = false
+		n.catchAll.name = name
 	} else {
-		name = pattern[1:index]
-		n.name = name
-		n.getChildMust(pattern[index]).addRoute(method, pattern[index:], handler)
+		n.catchAll = n.catchAll.clone()
 	}
+	if n.catchAll.name != name {
+		return rt.newError(n.catchAll.firstRoute(),
+			"catch-all "+name+" conflicts with catch-all "+n.catchAll.name)
+	}
+	return n.catchAll.handle(rt)
+}
 
+// validatePattern checks the syntax of pattern.
+func validatePattern(pattern string) error {
+	if pattern == "" || pattern[0] != '/' {
+		return errors.New("pattern must start with '/'")
+	}
+	for i := 0; i < len(pattern); i++ {
+		switch pattern[i] {
+		case ':':
+			name, constraint, rest := parseParam(pattern[i:])
+			if name == "" {
+				return fmt.Errorf("param at %d must be named", i)
+			}
+			if rest != "" && rest[0] == '<' {
+				return fmt.Errorf("param %s has an empty or unclosed constraint", name)
+			}
+			if constraint != "" {
+				if _, err := compileConstraint(constraint); err != nil {
+					return fmt.Errorf("param %s has an invalid constraint: %v", name, err)
+				}
+			}
+			if rest != "" && rest[0] == ':' {
+				return fmt.Errorf("param %s must be followed by static text before another param", name)
+			}
+			i = len(pattern) - len(rest) - 1
+		case '*':
+			if pattern[i-1] != '/' {
+				return errors.New("catch-all must follow a '/'")
+			}
+			name := pattern[i+1:]
+			if strings.Contains(name, "/") {
+				return errors.New("catch-all must be at the end of pattern")
+			}
+			if name == "" {
+				return errors.New("catch-all must be named")
+			}
+			for j := 0; j < len(name); j++ {
+				if !isNameChar(name[j]) {
+					return fmt.Errorf("catch-all has an invalid name %s", name)
+				}
+			}
+			return nil
+		}
+	}
+	return nil
+}
+
+// paramTypes are shorthands for frequently used param constraints.
+var paramTypes = map[string]string{
+	"int":   `-?[0-9]+`,
+	"uint":  `[0-9]+`,
+	"alpha": `[A-Za-z]+`,
+	"alnum": `[A-Za-z0-9]+`,
+	"hex":   `[0-9A-Fa-f]+`,
+	"uuid":  `[0-9A-Fa-f]{8}-[0-9A-Fa-f]{4}-[0-9A-Fa-f]{4}-[0-9A-Fa-f]{4}-[0-9A-Fa-f]{12}`,
+}
+
+// parseParam splits a pattern like ":name<constraint>.rest" into its parts.
+// The name consists of letters, digits and underscores only, so static text
+// may follow a param within the same segment. If the constraint is empty or
+// unclosed, rest starts with '<'.
+func parseParam(pattern string) (name, constraint, rest string) {
+	i := 1
+	for i < len(pattern) && isNameChar(pattern[i]) {
+		i++
+	}
+	name = pattern[1:i]
+	if i == len(pattern) || pattern[i] != '<' {
+		return name, "", pattern[i:]
+	}
+	depth := 0
+	for j := i; j < len(pattern); j++ {
+		switch pattern[j] {
+		case '\\':
+			j++
+		case '<':
+			depth++
+		case '>':
+			depth--
+			if depth == 0 {
+				if j == i+1 {
+					return name, "", pattern[i:]
+				}
+				return name, pattern[i+1 : j], pattern[j+1:]
+			}
+		}
+	}
+	return name, "", pattern[i:]
+}
+
+func isNameChar(c byte) bool {
+	return c == '_' || '0' <= c && c <= '9' || 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z'
+}
+
+func compileConstraint(constraint string) (*regexp.Regexp, error) {
+	if expr, ok := paramTypes[constraint]; ok {
+		constraint = expr
+	}
+	return regexp.Compile("^(?:" + constraint + ")$")
 }
 
 func longestPrefix(p1, p2 string) int {
 	i := 0
-	for i < len(p1) && i < len(p2) && p1[i] == p2[i] && p2[i] != ':' {
+	for i < len(p1) && i < len(p2) && p1[i] == p2[i] && p2[i] != ':' && p2[i] != '*' {
 		i++
 	}
 	return i
 }
 
-func (n *node) handle(method Method, handler http.Handler) {
-	if _, ok := n.handlers[method]; ok {
-		panic("duplicated handlers for same method")
+func (n *node) handle(rt *route) error {
+	routes := n.handlers[rt.Method]
+	i := len(routes)
+	if i > 0 && len(routes[i-1].matchers) == 0 {
+		if len(rt.matchers) == 0 {
+			return rt.newError(routes[i-1], "duplicated handlers for same method")
+		}
+		i--
+	}
+	// routes may be shared with published trees, never append in place.
+	c := make([]*route, 0, len(routes)+1)
+	c = append(c, routes[:i]...)
+	c = append(c, rt)
+	n.handlers[rt.Method] = append(c, routes[i:]...)
+	return nil
+}
+
+// clone returns a shallow copy of n, sharing children but not the containers
+// of them. Trees are copied on write: adding or removing a route clones nodes
+// along its path, so that published trees are never mutated.
+func (n *node) clone() *node {
+	c := *n
+	c.handlers = make(map[Method][]*route, len(n.handlers))
+	for method, routes := range n.handlers {
+		c.handlers[method] = routes
+	}
+	c.children = append([]*node(nil), n.children...)
+	c.params = append([]*node(nil), n.params...)
+	return &c
+}
+
+// removeRoute returns a copy of n without routes of method and pattern, and
+// the removed routes, or n itself and nil if there is no such route.
+func (n *node) removeRoute(method Method, pattern string) (*node, []*route) {
+	if routes, ok := n.handlers[method]; ok && routes[0].Pattern == pattern {
+		c := n.clone()
+		delete(c.handlers, method)
+		return c, routes
+	}
+	for i, child := range n.children {
+		if child, rt := child.removeRoute(method, pattern); rt != nil {
+			c := n.clone()
+			if child.isEmpty() {
+				c.indices = c.indices[:i] + c.indices[i+1:]
+				c.children = append(c.children[:i], c.children[i+1:]...)
+			} else {
+				child.priority -= len(rt)
+				c.children[i] = child
+				c.sortChild(i)
+			}
+			return c, rt
+		}
+	}
+	for i, child := range n.params {
+		if child, rt := child.removeRoute(method, pattern); rt != nil {
+			c := n.clone()
+			if child.isEmpty() {
+				c.params = append(c.params[:i], c.params[i+1:]...)
+			} else {
+				c.params[i] = child
+			}
+			return c, rt
+		}
 	}
-	n.handlers[method] = handler
+	if n.catchAll != nil {
+		if child, rt := n.catchAll.removeRoute(method, pattern); rt != nil {
+			c := n.clone()
+			c.catchAll = child
+			if child.isEmpty() {
+				c.catchAll = nil
+			}
+			return c, rt
+		}
+	}
+	return n, nil
+}
+
+func (n *node) isEmpty() bool {
+	return len(n.handlers) == 0 && len(n.children) == 0 && len(n.params) == 0 && n.catchAll == nil
+}
+
+// firstRoute returns the first route of n and its descendants.
+func (n *node) firstRoute() *route {
+	var first *route
+	n.walk(func(rt *route) error {
+		first = rt
+		return errFound
+	})
+	return first
 }
 
 func (n *node) splitAt(index int) {
-	child := newNode(n, n.pattern[index:])
+	child := newNode(n.pattern[index:])
 	child.handlers = n.handlers
+	child.indices = n.indices
 	child.children = n.children
-	child.named = false
+	child.priority = n.priority
+	child.params = n.params
+	child.catchAll = n.catchAll
 	child.empty = false
 
-	n.handlers = map[Method]http.Handler{}
-	n.children = map[byte]*node{n.pattern[index]: child}
+	n.handlers = map[Method][]*route{}
+	n.indices = n.pattern[index : index+1]
+	n.children = []*node{child}
+	n.params = nil
+	n.catchAll = nil
 	n.pattern = n.pattern[:index]
 }
 
-func (n *node) getChildMust(c byte) *node {
-	if child, ok := n.children[c]; ok {
-		return child
+// getChildMust returns a copy of the static child starting with c, or a new
+// one, and its index.
+func (n *node) getChildMust(c byte) (*node, int) {
+	if i := strings.IndexByte(n.indices, c); i != -1 {
+		child := n.children[i].clone()
+		n.children[i] = child
+		return child, i
+	}
+	child := newNode("")
+	n.indices += string(c)
+	n.children = append(n.children, child)
+	return child, len(n.children) - 1
+}
+
+// sortChild moves the static child at index i after its priority changed,
+// keeping children ordered by priority.
+func (n *node) sortChild(i int) {
+	child, c := n.children[i], n.indices[i]
+	j := i
+	for j > 0 && n.children[j-1].priority < child.priority {
+		j--
+	}
+	for j < len(n.children)-1 && n.children[j+1].priority > child.priority {
+		j++
+	}
+	if j == i {
+		return
 	}
-	child := newNode(n, "")
-	n.children[c] = child
-	if c == ':' {
-		child.named = true
+	indices := []byte(n.indices)
+	if j < i {
+		copy(n.children[j+1:i+1], n.children[j:i])
+		copy(indices[j+1:i+1], indices[j:i])
+	} else {
+		copy(n.children[i:j], n.children[i+1:j+1])
+		copy(indices[i:j], indices[i+1:j+1])
 	}
-	return child
+	n.children[j], indices[j] = child, c
+	n.indices = string(indices)
 }
 
-var (
-	errNotFound         = errors.New("Not Found")
-	errMethodNotAllowed = errors.New("Method Not Allowed")
-)
+// getParamChild returns the param child with the same name and constraint,
+// or creates a new one. Params with the same constraint but different names
+// conflict. Constrained params are kept ahead of unconstrained ones, so that
+// they are tried first when matching.
+func (n *node) getParamChild(name, constraint string, rt *route) (*node, error) {
+	for i, child := range n.params {
+		if child.constraint != constraint {
+			continue
+		}
+		if child.name != name {
+			return nil, rt.newError(child.firstRoute(),
+				"param "+name+" conflicts with param "+child.name)
+		}
+		child = child.clone()
+		n.params[i] = child
+		return child, nil
+	}
+	child := newNode("")
+	child.kind = paramNode
+	child.empty = false
+	child.name = name
+	if constraint != "" {
+		re, err := compileConstraint(constraint)
+		if err != nil {
+			return nil, err
+		}
+		child.constraint = constraint
+		child.re = re
+	}
+	i := len(n.params)
+	if constraint != "" {
+		for i > 0 && n.params[i-1].constraint == "" {
+			i--
+		}
+	}
+	n.params = append(n.params, nil)
+	copy(n.params[i+1:], n.params[i:])
+	n.params[i] = child
+	return child, nil
+}
+
+var errFound = errors.New("Found")
 
-func (n *node) match(p Params, method Method, pattern string) (Params, http.Handler, error) {
-	if n.named {
-		return n.matchNamed(p, method, pattern)
+// lookup holds the state of matching a path against the tree.
+type lookup struct {
+	method Method
+	params *params
+	// req is the request routes are matched for by their matchers, if not
+	// nil. route is the route picked then, and status the largest status of
+	// failed matchers, see pickRoute.
+	req    *http.Request
+	route  *route
+	status int
+	// notAllowed reports whether a route matched the path but not the method.
+	notAllowed bool
+	// allowed collects methods of all routes matching the path if not nil,
+	// and matching never succeeds then.
+	allowed map[Method]bool
+	// fold enables comparing static text ignoring ASCII case. If fixed is
+	// not nil, the path rewritten in the case of the matched route is
+	// appended to it.
+	fold  bool
+	fixed []byte
+}
+
+// match returns the node serving l.method for path, or nil. Static children
+// take precedence over params, and params over catch-all. If a branch fails,
+// even deep down or only for the method, the next one is tried.
+func (n *node) match(l *lookup, path string) *node {
+	switch n.kind {
+	case catchAllNode:
+		return n.matchCatchAll(l, path)
+	case paramNode:
+		return n.matchNamed(l, path)
 	}
-	i := longestPrefix(n.pattern, pattern)
-	if i < len(n.pattern) {
-		return p, nil, errNotFound
+	if len(path) < len(n.pattern) {
+		return nil
 	}
-	if i < len(pattern) {
-		if child, ok := n.children[pattern[i]]; ok {
-			if p, h, err := child.match(p, method, pattern[i:]); err == nil || err == errMethodNotAllowed {
-				return p, h, err
-			}
-			if child, ok := n.children[':']; ok {
-				return child.match(p, method, pattern[i:])
-			}
-			return p, nil, errNotFound
+	if l.fold {
+		if !equalFoldASCII(path[:len(n.pattern)], n.pattern) {
+			return nil
 		}
-		if child, ok := n.children[':']; ok {
-			return child.match(p, method, pattern[i:])
+	} else if path[:len(n.pattern)] != n.pattern {
+		return nil
+	}
+	mark := len(l.fixed)
+	if l.fixed != nil {
+		l.fixed = append(l.fixed, n.pattern...)
+	}
+	path = path[len(n.pattern):]
+	var leaf *node
+	if path != "" {
+		leaf = n.matchChildren(l, path)
+	} else if n.accept(l) {
+		leaf = n
+	} else if n.catchAll != nil {
+		leaf = n.catchAll.match(l, "")
+	}
+	if leaf == nil {
+		l.fixed = l.fixed[:mark]
+	}
+	return leaf
+}
+
+func (n *node) matchChildren(l *lookup, path string) *node {
+	if leaf := n.matchStatic(l, path); leaf != nil {
+		return leaf
+	}
+	for _, child := range n.params {
+		if leaf := child.match(l, path); leaf != nil {
+			return leaf
 		}
-		return p, nil, errNotFound
 	}
-	return n.handleMethod(p, method)
+	if n.catchAll != nil {
+		return n.catchAll.match(l, path)
+	}
+	return nil
 }
 
-func (n *node) matchNamed(p Params, method Method, pattern string) (Params, http.Handler, error) {
-	index := strings.Index(pattern, "/")
-	if index == -1 {
-		value := pattern
-		return n.handleMethod(withValue(p, n.name, value), method)
+// matchStatic tries the static child starting with the first byte of path.
+func (n *node) matchStatic(l *lookup, path string) *node {
+	if i := strings.IndexByte(n.indices, path[0]); i != -1 {
+		if leaf := n.children[i].match(l, path); leaf != nil {
+			return leaf
+		}
 	}
-	value := pattern[:index]
-	if child, ok := n.children['/']; ok {
-		return child.match(withValue(p, n.name, value), method, pattern[index:])
+	if c := swapCase(path[0]); l.fold && c != path[0] {
+		if i := strings.IndexByte(n.indices, c); i != -1 {
+			return n.children[i].match(l, path)
+		}
 	}
-	return p, nil, errNotFound
+	return nil
 }
 
-func (n *node) handleMethod(p Params, method Method) (Params, http.Handler, error) {
+func (n *node) hasStatic(c byte, fold bool) bool {
+	if strings.IndexByte(n.indices, c) != -1 {
+		return true
+	}
+	return fold && strings.IndexByte(n.indices, swapCase(c)) != -1
+}
+
+// matchNamed matches a non-empty value within the current segment. If static
+// text follows the param in the same segment, e.g. "/:name.:ext", routes
+// continuing with the static text take precedence, and the longest value is
+// tried first, so "a.tar.gz" gives name "a.tar" and ext "gz".
+func (n *node) matchNamed(l *lookup, path string) *node {
+	end := strings.IndexByte(path, '/')
+	if end == -1 {
+		end = len(path)
+	}
+	saved, mark := l.params.Len(), len(l.fixed)
+	i := end
+	if i == len(path) {
+		i--
+	}
+	for ; i > 0; i-- {
+		if !n.hasStatic(path[i], l.fold) {
+			continue
+		}
+		value := path[:i]
+		if n.re != nil && !n.re.MatchString(value) {
+			continue
+		}
+		l.params.truncate(saved)
+		l.params.add(n.name, value)
+		if l.fixed != nil {
+			l.fixed = append(l.fixed[:mark], value...)
+		}
+		if leaf := n.matchStatic(l, path[i:]); leaf != nil {
+			return leaf
+		}
+	}
+	l.params.truncate(saved)
+	l.fixed = l.fixed[:mark]
+	if end < len(path) || end == 0 || n.re != nil && !n.re.MatchString(path) {
+		return nil
+	}
+	return n.matchCatchAll(l, path)
+}
+
+// matchCatchAll matches the whole path as the value of n.
+func (n *node) matchCatchAll(l *lookup, path string) *node {
+	saved := l.params.Len()
+	l.params.add(n.name, path)
+	if !n.accept(l) {
+		l.params.truncate(saved)
+		return nil
+	}
+	if l.fixed != nil {
+		l.fixed = append(l.fixed, path...)
+	}
+	return n
+}
+
+// accept reports whether n has a handler for l.method, accepting l.req by
+// its matchers. If matchers reject the request, matching goes on with the
+// next branch like for a path mismatch.
+func (n *node) accept(l *lookup) bool {
 	if len(n.handlers) == 0 {
-		return p, nil, errNotFound
+		return false
+	}
+	if l.allowed != nil {
+		for method := range n.handlers {
+			if method == ANY {
+				for _, method := range Methods {
+					l.allowed[method] = true
+				}
+				continue
+			}
+			l.allowed[method] = true
+		}
+		if _, ok := n.handlers[GET]; ok {
+			l.allowed[HEAD] = true
+		}
+		return false
+	}
+	routes := n.routes(l.method)
+	if routes == nil {
+		l.notAllowed = true
+		return false
+	}
+	if l.req == nil {
+		return true
+	}
+	rt, status := pickRoute(routes, l.req)
+	if rt == nil {
+		if status > l.status {
+			l.status = status
+		}
+		return false
+	}
+	l.route = rt
+	return true
+}
+
+// swapCase swaps the case of ASCII letters.
+func swapCase(c byte) byte {
+	switch {
+	case 'a' <= c && c <= 'z':
+		return c - 'a' + 'A'
+	case 'A' <= c && c <= 'Z':
+		return c - 'A' + 'a'
+	}
+	return c
+}
+
+func equalFoldASCII(s, t string) bool {
+	if len(s) != len(t) {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		c1, c2 := s[i], t[i]
+		if 'A' <= c1 && c1 <= 'Z' {
+			c1 += 'a' - 'A'
+		}
+		if 'A' <= c2 && c2 <= 'Z' {
+			c2 += 'a' - 'A'
+		}
+		if c1 != c2 {
+			return false
+		}
 	}
-	if h, ok := n.handlers[method]; ok {
-		return p, h, nil
+	return true
+}
+
+// routes returns routes for method. HEAD requests are handled by GET routes
+// if there is no HEAD route, and ANY routes handle methods without routes.
+func (n *node) routes(method Method) []*route {
+	if routes, ok := n.handlers[method]; ok {
+		return routes
 	}
-	return p, nil, errMethodNotAllowed
+	if n.headFallback(method) {
+		return n.handlers[GET]
+	}
+	return n.handlers[ANY]
 }
 
-func (n *node) printTree(prefix string) {
-	pattern := n.pattern
-	if n.named {
-		pattern = ":" + n.name
+// headFallback reports whether requests of method are HEAD requests handled
+// by GET routes.
+func (n *node) headFallback(method Method) bool {
+	if method != HEAD {
+		return false
+	}
+	if _, ok := n.handlers[HEAD]; ok {
+		return false
 	}
+	_, ok := n.handlers[GET]
+	return ok
+}
+
+// walk calls fn for routes of n and its descendants in a stable order.
+func (n *node) walk(fn func(*route) error) error {
+	methods := make([]Method, 0, len(n.handlers))
 	for method := range n.handlers {
-		fmt.Println(method, prefix+pattern)
+		methods = append(methods, method)
+	}
+	sortMethods(methods)
+	for _, method := range methods {
+		for _, rt := range n.handlers[method] {
+			if err := fn(rt); err != nil {
+				return err
+			}
+		}
+	}
+	// children are ordered by priority, walk them by their first bytes.
+	order := make([]int, len(n.children))
+	for i := range order {
+		order[i] = i
+	}
+	sort.Slice(order, func(i, j int) bool { return n.indices[order[i]] < n.indices[order[j]] })
+	for _, i := range order {
+		if err := n.children[i].walk(fn); err != nil {
+			return err
+		}
+	}
+	for _, child := range n.params {
+		if err := child.walk(fn); err != nil {
+			return err
+		}
 	}
-	for _, child := range n.children {
-		child.printTree(prefix + pattern)
+	if n.catchAll != nil {
+		return n.catchAll.walk(fn)
 	}
+	return nil
 }
@@ -90,7 +90,8 @@ func TestNamedNode(t *testing.T) {
 	}{
 		{POST, "/fuck/you", 200},
 		{DELETE, "/fuck/you", 200},
-		{HEAD, "/fuck/you", 405},
+		{HEAD, "/fuck/you", 200},
+		{HEAD, "/fuck/her/again", 405},
 		{GET, "/fuck/her", 404},
 		{GET, "/fuck/me", 200},
 		{POST, "/fuck/her", 404},
@@ -111,3 +112,788 @@ func TestNamedNode(t *testing.T) {
 		}
 	}
 }
+
+func TestCatchAllNode(t *testing.T) {
+	router := NewRouter()
+
+	routes := []struct {
+		method  Method
+		pattern string
+	}{
+		{GET, "/static/*filepath"},
+		{GET, "/static/index.html"},
+		{GET, "/repos/:owner/*path"},
+		{GET, "/repos/:owner/settings"},
+		{POST, "/files/*path"},
+	}
+	for _, r := range routes {
+		router.AddRoute(r.method, r.pattern, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			for _, name := range []string{"owner", "filepath", "path"} {
+				if value, ok := ParamsOfReq(r, name); ok {
+					fmt.Fprintf(w, "%s=%s;", name, value)
+				}
+			}
+		}))
+	}
+
+	cases := []struct {
+		method  Method
+		pattern string
+		code    int
+		body    string
+	}{
+		{GET, "/static/css/main.css", 200, "filepath=css/main.css;"},
+		{GET, "/static/", 200, "filepath=;"},
+		{GET, "/static/index.html", 200, ""},
+		{GET, "/static", 404, ""},
+		{GET, "/repos/alice/src/hodor/node.go", 200, "owner=alice;path=src/hodor/node.go;"},
+		{GET, "/repos/alice/settings", 200, "owner=alice;"},
+		{GET, "/files/a/b", 405, ""},
+		{POST, "/files/a/b", 200, "path=a/b;"},
+	}
+	for _, c := range cases {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(c.method.String(), c.pattern, nil)
+		router.ServeHTTP(w, req)
+		if got, exp := w.Code, c.code; got != exp {
+			t.Errorf("%s %s code not match. exp: %d, got: %d ",
+				c.method, c.pattern, exp, got)
+		}
+		if c.code == 200 {
+			if got, exp := w.Body.String(), c.body; got != exp {
+				t.Errorf("%s %s response not match. exp: %s, got: %s ",
+					c.method, c.pattern, exp, got)
+			}
+		}
+	}
+}
+
+func TestCatchAllInvalid(t *testing.T) {
+	patterns := []string{
+		"/static/*filepath/more",
+		"/static/prefix*filepath",
+		"/static/*",
+	}
+	for _, pattern := range patterns {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("%s should be rejected", pattern)
+				}
+			}()
+			NewRouter().AddRoute(GET, pattern, defaultHandler)
+		}()
+	}
+}
+
+func TestParamConstraint(t *testing.T) {
+	router := NewRouter()
+
+	routes := []struct {
+		method  Method
+		pattern string
+	}{
+		{GET, "/users/:id<int>"},
+		{GET, "/users/:name"},
+		{GET, "/files/:name<[a-z0-9-]+>"},
+		{GET, "/orders/:id<uuid>/items"},
+	}
+	for _, r := range routes {
+		pattern := r.pattern
+		router.AddRoute(r.method, pattern, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, pattern)
+		}))
+	}
+
+	cases := []struct {
+		method  Method
+		pattern string
+		code    int
+		body    string
+	}{
+		{GET, "/users/42", 200, "/users/:id<int>"},
+		{GET, "/users/-42", 200, "/users/:id<int>"},
+		{GET, "/users/alice", 200, "/users/:name"},
+		{GET, "/files/hodor-1", 200, "/files/:name<[a-z0-9-]+>"},
+		{GET, "/files/Hodor", 404, ""},
+		{GET, "/orders/123e4567-e89b-12d3-a456-426655440000/items", 200, "/orders/:id<uuid>/items"},
+		{GET, "/orders/42/items", 404, ""},
+	}
+	for _, c := range cases {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(c.method.String(), c.pattern, nil)
+		router.ServeHTTP(w, req)
+		if got, exp := w.Code, c.code; got != exp {
+			t.Errorf("%s %s code not match. exp: %d, got: %d ",
+				c.method, c.pattern, exp, got)
+		}
+		if got, exp := w.Body.String(), c.body; c.code == 200 && got != exp {
+			t.Errorf("%s %s response not match. exp: %s, got: %s ",
+				c.method, c.pattern, exp, got)
+		}
+	}
+}
+
+func TestParamConstraintInvalid(t *testing.T) {
+	patterns := []string{
+		"/users/:id<[0-9>",
+		"/users/:id<>",
+		"/users/:id<(>",
+		"/users/:<int>",
+	}
+	for _, pattern := range patterns {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("%s should be rejected", pattern)
+				}
+			}()
+			NewRouter().AddRoute(GET, pattern, defaultHandler)
+		}()
+	}
+}
+
+func TestMultiParamSegment(t *testing.T) {
+	router := NewRouter()
+
+	patterns := []string{
+		"/files/:name.:ext",
+		"/files/:name",
+		"/v:version/items",
+		"/user-:id<int>",
+		"/user-:id<int>/:from-:to",
+	}
+	for _, pattern := range patterns {
+		router.AddRoute(GET, pattern, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			for _, name := range []string{"name", "ext", "version", "id", "from", "to"} {
+				if value, ok := ParamsOfReq(r, name); ok {
+					fmt.Fprintf(w, "%s=%s;", name, value)
+				}
+			}
+		}))
+	}
+
+	cases := []struct {
+		pattern string
+		code    int
+		body    string
+	}{
+		{"/files/hodor.json", 200, "name=hodor;ext=json;"},
+		{"/files/hodor.tar.gz", 200, "name=hodor.tar;ext=gz;"},
+		{"/files/hodor", 200, "name=hodor;"},
+		{"/files/hodor.", 200, "name=hodor.;"},
+		{"/v2/items", 200, "version=2;"},
+		{"/v/items", 404, ""},
+		{"/user-42", 200, "id=42;"},
+		{"/user-abc", 404, ""},
+		{"/user-42/2017-2018", 200, "id=42;from=2017;to=2018;"},
+	}
+	for _, c := range cases {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest("GET", c.pattern, nil)
+		router.ServeHTTP(w, req)
+		if got, exp := w.Code, c.code; got != exp {
+			t.Errorf("GET %s code not match. exp: %d, got: %d ", c.pattern, exp, got)
+		}
+		if got, exp := w.Body.String(), c.body; c.code == 200 && got != exp {
+			t.Errorf("GET %s response not match. exp: %s, got: %s ", c.pattern, exp, got)
+		}
+	}
+
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Errorf("adjacent params should be rejected")
+			}
+		}()
+		NewRouter().AddRoute(GET, "/:name:ext", defaultHandler)
+	}()
+}
+
+func TestAllowHeader(t *testing.T) {
+	router := NewRouter()
+	router.AddRoute(POST, "/users/:id", defaultHandler)
+	router.AddRoute(GET, "/users/:id", defaultHandler)
+	router.AddRoute(DELETE, "/users/:id", defaultHandler)
+	router.AddRoute(OPTIONS, "/options", defaultHandler)
+	router.AddRoute(GET, "/a", defaultHandler)
+	router.AddRoute(OPTIONS, "/a", defaultHandler)
+	router.Handler405 = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		fmt.Fprint(w, joinMethods(AllowedMethodsOfReq(r)))
+	})
+
+	cases := []struct {
+		method        Method
+		pattern       string
+		handleOptions bool
+		code          int
+		allow         string
+	}{
+		{PUT, "/users/42", false, 405, "GET, HEAD, POST, DELETE"},
+		{OPTIONS, "/users/42", false, 405, "GET, HEAD, POST, DELETE"},
+		{PUT, "/users/42", true, 405, "OPTIONS, GET, HEAD, POST, DELETE"},
+		{OPTIONS, "/users/42", true, 204, "OPTIONS, GET, HEAD, POST, DELETE"},
+		{OPTIONS, "/options", true, 200, ""},
+		{OPTIONS, "/nothing", true, 404, ""},
+		{POST, "/a", true, 405, "OPTIONS, GET, HEAD"},
+	}
+	for _, c := range cases {
+		router.HandleOPTIONS = c.handleOptions
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(c.method.String(), c.pattern, nil)
+		router.ServeHTTP(w, req)
+		if got, exp := w.Code, c.code; got != exp {
+			t.Errorf("%s %s code not match. exp: %d, got: %d ",
+				c.method, c.pattern, exp, got)
+		}
+		if got, exp := w.Header().Get("Allow"), c.allow; got != exp {
+			t.Errorf("%s %s Allow not match. exp: %s, got: %s ",
+				c.method, c.pattern, exp, got)
+		}
+		if got, exp := w.Body.String(), c.allow; c.code == 405 && got != exp {
+			t.Errorf("%s %s allowed methods not match. exp: %s, got: %s ",
+				c.method, c.pattern, exp, got)
+		}
+	}
+}
+
+func TestHeadFallback(t *testing.T) {
+	router := NewRouter()
+	router.AddRoute(GET, "/hodor", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Hodor", "hodor")
+		fmt.Fprint(w, "hodor hodor")
+		if size := w.(ResponseWriter).Size(); size != 11 {
+			t.Errorf("size not match. exp: 11, got: %d", size)
+		}
+	}))
+	router.AddRoute(GET, "/stream", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "abc")
+		w.(http.Flusher).Flush()
+		fmt.Fprint(w, "defgh")
+	}))
+	router.AddRoute(GET, "/explicit", defaultHandler)
+	router.AddRoute(HEAD, "/explicit", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Hodor", "explicit")
+	}))
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("HEAD", "/hodor", nil))
+	if w.Code != 200 {
+		t.Errorf("HEAD /hodor code not match. exp: 200, got: %d", w.Code)
+	}
+	if w.Body.Len() != 0 {
+		t.Errorf("HEAD /hodor body should be discarded, got: %s", w.Body.String())
+	}
+	if got := w.Header().Get("X-Hodor"); got != "hodor" {
+		t.Errorf("HEAD /hodor header not match. exp: hodor, got: %s", got)
+	}
+	if got := w.Header().Get("Content-Length"); got != "11" {
+		t.Errorf("HEAD /hodor Content-Length not match. exp: 11, got: %s", got)
+	}
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("HEAD", "/stream", nil))
+	if w.Code != 200 || !w.Flushed || w.Body.Len() != 0 {
+		t.Errorf("HEAD /stream should be flushed with code 200 and no body, got: %d %v %q",
+			w.Code, w.Flushed, w.Body.String())
+	}
+	if got, ok := w.Result().Header["Content-Length"]; ok {
+		t.Errorf("HEAD /stream should have no Content-Length after flushing, got: %s", got)
+	}
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("HEAD", "/explicit", nil))
+	if got := w.Header().Get("X-Hodor"); got != "explicit" {
+		t.Errorf("HEAD /explicit header not match. exp: explicit, got: %s", got)
+	}
+}
+
+func TestAnyMethod(t *testing.T) {
+	router := NewRouter()
+	route := BuildRoute(router)
+	echo := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, r.Method)
+	})
+	route.Any().Pattern("/any").Handler(echo)
+	route.Delete().Pattern("/any").Handler(defaultHandler)
+	route.Match(WebDAVMethods...).Pattern("/dav/*path").Handler(echo)
+	route.Get().Pattern("/dav/*path").Handler(echo)
+	route.Match(PUT, "PURGE").Pattern("/cache").Handler(echo)
+
+	cases := []struct {
+		method Method
+		path   string
+		code   int
+		body   string
+		allow  string
+	}{
+		{GET, "/any", 200, "GET", ""},
+		{"PURGE", "/any", 200, "PURGE", ""},
+		{DELETE, "/any", 200, "/any", ""},
+		{PROPFIND, "/dav/a/b", 200, "PROPFIND", ""},
+		{MKCOL, "/dav/a", 200, "MKCOL", ""},
+		{POST, "/dav/a", 405, "", "GET, HEAD, COPY, LOCK, MKCOL, MOVE, PROPFIND, PROPPATCH, UNLOCK"},
+		{"PURGE", "/cache", 200, "PURGE", ""},
+		{GET, "/cache", 405, "", "PUT, PURGE"},
+	}
+	for _, c := range cases {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(c.method.String(), c.path, nil))
+		if got, exp := w.Code, c.code; got != exp {
+			t.Errorf("%s %s code not match. exp: %d, got: %d ", c.method, c.path, exp, got)
+			continue
+		}
+		if got, exp := w.Header().Get("Allow"), c.allow; got != exp {
+			t.Errorf("%s %s Allow not match. exp: %s, got: %s ", c.method, c.path, exp, got)
+		}
+		if got, exp := w.Body.String(), c.body; c.code == 200 && got != exp {
+			t.Errorf("%s %s body not match. exp: %s, got: %s ", c.method, c.path, exp, got)
+		}
+	}
+
+	for _, method := range []Method{"", "GET /", "FOO:BAR"} {
+		if err := router.TryAddRoute(method, "/invalid", defaultHandler); err == nil {
+			t.Errorf("method %q should be rejected", method)
+		}
+	}
+}
+
+func TestRedirect(t *testing.T) {
+	router := NewRouter()
+	router.RedirectTrailingSlash = true
+	router.RedirectFixedPath = true
+	router.RedirectCaseInsensitive = true
+	router.AddRoute(GET, "/users", defaultHandler)
+	router.AddRoute(POST, "/users", defaultHandler)
+	router.AddRoute(GET, "/users/:name/", defaultHandler)
+	router.AddRoute(GET, "/static/*filepath", defaultHandler)
+
+	cases := []struct {
+		method   Method
+		pattern  string
+		code     int
+		location string
+	}{
+		{GET, "/users", 200, ""},
+		{GET, "/users/", 301, "/users"},
+		{HEAD, "/users/", 301, "/users"},
+		{POST, "/users/", 308, "/users"},
+		{GET, "/users/?page=2", 301, "/users?page=2"},
+		{GET, "/users/Alice", 301, "/users/Alice/"},
+		{GET, "/USERS/Alice/", 301, "/users/Alice/"},
+		{GET, "/a/../users", 301, "/users"},
+		{GET, "//users//Alice/", 301, "/users/Alice/"},
+		{GET, "/Static/CSS/main.css", 301, "/static/CSS/main.css"},
+		{PUT, "/users/", 404, ""},
+		{GET, "/nothing", 404, ""},
+	}
+	for _, c := range cases {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(c.method.String(), c.pattern, nil)
+		router.ServeHTTP(w, req)
+		if got, exp := w.Code, c.code; got != exp {
+			t.Errorf("%s %s code not match. exp: %d, got: %d ",
+				c.method, c.pattern, exp, got)
+		}
+		if got, exp := w.Header().Get("Location"), c.location; got != exp {
+			t.Errorf("%s %s location not match. exp: %s, got: %s ",
+				c.method, c.pattern, exp, got)
+		}
+	}
+}
+
+func TestMatchModes(t *testing.T) {
+	router := NewRouter()
+	route := BuildRoute(router)
+	echo := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ps := ParamListOfReq(r)
+		for i := 0; i < ps.Len(); i++ {
+			_, v := ps.At(i)
+			fmt.Fprintf(w, "[%s]", v)
+		}
+	})
+	route.Get().Pattern("/buckets/:bucket/objects/:key").Handler(echo)
+	route.Get().Pattern("/files/*path").Handler(echo)
+	route.Get().Pattern("/Users/:name/Profile").Handler(echo)
+
+	cases := []struct {
+		escaped bool
+		fold    bool
+		path    string
+		code    int
+		body    string
+	}{
+		{false, false, "/buckets/b/objects/a%2Fb", 404, ""},
+		{true, false, "/buckets/b/objects/a%2Fb", 200, "[b][a/b]"},
+		{true, false, "/buckets/b%20c/objects/a%252F", 200, "[b c][a%2F]"},
+		{true, false, "/files/a%2Fb/c", 200, "[a/b/c]"},
+		{false, false, "/users/Bob/profile", 404, ""},
+		{false, true, "/users/Bob/profile", 200, "[Bob]"},
+		{false, true, "/USERS/bob/PROFILE", 200, "[bob]"},
+		{true, true, "/users/a%2Fb/profile", 200, "[a/b]"},
+	}
+	for _, c := range cases {
+		router.UseEscapedPath = c.escaped
+		router.CaseInsensitive = c.fold
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest("GET", c.path, nil))
+		if got, exp := w.Code, c.code; got != exp {
+			t.Errorf("GET %s code not match. exp: %d, got: %d", c.path, exp, got)
+			continue
+		}
+		if got, exp := w.Body.String(), c.body; c.code == 200 && got != exp {
+			t.Errorf("GET %s body not match. exp: %s, got: %s", c.path, exp, got)
+		}
+	}
+
+	router.CaseInsensitive = true
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("POST", "/users/bob/profile", nil))
+	if got, exp := w.Header().Get("Allow"), "GET, HEAD"; got != exp {
+		t.Errorf("POST /users/bob/profile Allow not match. exp: %s, got: %s", exp, got)
+	}
+}
+
+func TestChildrenPriority(t *testing.T) {
+	router := NewRouter()
+	router.AddRoute(GET, "/a", defaultHandler)
+	router.AddRoute(GET, "/b/1", defaultHandler)
+	router.AddRoute(GET, "/b/2", defaultHandler)
+	router.AddRoute(GET, "/c/1", defaultHandler)
+	router.AddRoute(GET, "/c/2", defaultHandler)
+	router.AddRoute(GET, "/c/3", defaultHandler)
+
+	root := router.load().root
+	if got, exp := root.indices, "cba"; got != exp {
+		t.Errorf("indices not match. exp: %s, got: %s", exp, got)
+	}
+	for i, child := range root.children {
+		if got, exp := child.pattern[0], root.indices[i]; got != exp {
+			t.Errorf("child %d not match. exp: %c, got: %c", i, exp, got)
+		}
+	}
+
+	router.RemoveRoute(GET, "/c/1")
+	router.RemoveRoute(GET, "/c/2")
+	root = router.load().root
+	if got, exp := root.indices, "bca"; got != exp {
+		t.Errorf("indices after removing not match. exp: %s, got: %s", exp, got)
+	}
+	for _, path := range []string{"/a", "/b/1", "/b/2", "/c/3"} {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+		if got, exp := w.Code, 200; got != exp {
+			t.Errorf("GET %s code not match. exp: %d, got: %d", path, exp, got)
+		}
+	}
+}
+
+func TestTryAddRoute(t *testing.T) {
+	router := NewRouter()
+	router.AddRoute(GET, "/users/:id", defaultHandler)
+	router.AddRoute(GET, "/static/*filepath", defaultHandler)
+	router.AddRoute(GET, "/posts", defaultHandler, nameOption("posts"))
+
+	cases := []struct {
+		method   Method
+		pattern  string
+		name     string
+		conflict string
+	}{
+		{GET, "", "", ""},
+		{GET, "users", "", ""},
+		{GET, ":id", "", ""},
+		{GET, "/users/:", "", ""},
+		{GET, "/users/:<int>", "", ""},
+		{GET, "/users/:id<>", "", ""},
+		{GET, "/users/:id<int", "", ""},
+		{GET, "/users/:id<(>", "", ""},
+		{GET, "/users/:id:name", "", ""},
+		{GET, "/users/:id*name", "", ""},
+		{GET, "/files/*", "", ""},
+		{GET, "/files/*path/more", "", ""},
+		{GET, "/files/*path.txt", "", ""},
+		{GET, "/a/:id/b/:id", "", ""},
+		{GET, "/a/:id.:id", "", ""},
+		{GET, "/a/:path/*path", "", ""},
+		{GET, "/users/:id", "", "GET /users/:id"},
+		{POST, "/users/:name", "", "GET /users/:id"},
+		{POST, "/users/:name/posts", "", "GET /users/:id"},
+		{GET, "/static/*path", "", "GET /static/*filepath"},
+		{GET, "/articles", "posts", "GET /posts"},
+	}
+	for _, c := range cases {
+		var filters []Filter
+		if c.name != "" {
+			filters = append(filters, nameOption(c.name))
+		}
+		err := router.TryAddRoute(c.method, c.pattern, defaultHandler, filters...)
+		if err == nil {
+			t.Errorf("%s %s should be rejected", c.method, c.pattern)
+			continue
+		}
+		re, ok := err.(*RouteError)
+		if !ok {
+			t.Errorf("%s %s error should be a *RouteError, got: %T", c.method, c.pattern, err)
+			continue
+		}
+		conflict := ""
+		if re.Conflict != nil {
+			conflict = re.Conflict.Method.String() + " " + re.Conflict.Pattern
+		}
+		if got, exp := conflict, c.conflict; got != exp {
+			t.Errorf("%s %s conflict not match. exp: %s, got: %s", c.method, c.pattern, exp, got)
+		}
+	}
+
+	if err := router.TryAddRoute(GET, "/users/:id", defaultHandler, WithHost(":id.example.com")); err == nil {
+		t.Errorf("param duplicated in host and pattern should be rejected")
+	}
+
+	for _, pattern := range []string{"/users/:id<int>", "/users/:id/posts", "/static/"} {
+		if err := router.TryAddRoute(POST, pattern, defaultHandler); err != nil {
+			t.Errorf("POST %s should be added, got: %v", pattern, err)
+		}
+	}
+}
+
+func TestRemoveRoute(t *testing.T) {
+	router := NewRouter()
+	router.AddRoute(GET, "/users/:id", defaultHandler, nameOption("user"))
+	router.AddRoute(DELETE, "/users/:id", defaultHandler, nameOption("user"))
+	router.AddRoute(GET, "/users/new", defaultHandler)
+	router.AddRoute(GET, "/static/*filepath", defaultHandler)
+
+	cases := []struct {
+		method  Method
+		pattern string
+		removed bool
+		path    string
+		code    int
+	}{
+		{GET, "/users/new", true, "/users/new", 200},
+		{GET, "/users/new", false, "/users/new", 200},
+		{GET, "/users/:id", true, "/users/42", 405},
+		{GET, "/static/*filepath", true, "/static/main.css", 404},
+		{DELETE, "/users/:id", true, "/users/42", 404},
+	}
+	for _, c := range cases {
+		if got, exp := router.RemoveRoute(c.method, c.pattern), c.removed; got != exp {
+			t.Errorf("remove %s %s not match. exp: %v, got: %v", c.method, c.pattern, exp, got)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(c.method.String(), c.path, nil))
+		if got, exp := w.Code, c.code; got != exp {
+			t.Errorf("%s %s code not match. exp: %d, got: %d", c.method, c.path, exp, got)
+		}
+		if c.pattern == "/users/:id" && c.method == GET {
+			if _, err := router.URL("user", "id", "42"); err != nil {
+				t.Errorf("name should be kept by DELETE /users/:id, got: %v", err)
+			}
+		}
+	}
+	if _, err := router.URL("user", "id", "42"); err == nil {
+		t.Errorf("name should be removed with routes")
+	}
+	if err := router.TryAddRoute(GET, "/users/:name", defaultHandler); err != nil {
+		t.Errorf("GET /users/:name should be added after removing /users/:id, got: %v", err)
+	}
+}
+
+func TestConcurrentRoutes(t *testing.T) {
+	router := NewRouter()
+	router.AddRoute(GET, "/users/:id", defaultHandler)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 100; i++ {
+			pattern := fmt.Sprintf("/flags/%d/:id", i)
+			router.AddRoute(GET, pattern, defaultHandler)
+			if i%2 == 0 {
+				router.RemoveRoute(GET, pattern)
+			}
+		}
+	}()
+	for i := 0; i < 100; i++ {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest("GET", "/users/42", nil))
+		if w.Code != 200 {
+			t.Errorf("GET /users/42 code not match. exp: 200, got: %d", w.Code)
+		}
+	}
+	<-done
+
+	for i := 0; i < 100; i++ {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest("GET", fmt.Sprintf("/flags/%d/42", i), nil))
+		exp := 200
+		if i%2 == 0 {
+			exp = 404
+		}
+		if got := w.Code; got != exp {
+			t.Errorf("GET /flags/%d/42 code not match. exp: %d, got: %d", i, exp, got)
+		}
+	}
+}
+
+func TestBacktracking(t *testing.T) {
+	router := NewRouter()
+
+	routes := []struct {
+		method  Method
+		pattern string
+	}{
+		{GET, "/users/new"},
+		{DELETE, "/users/:id"},
+		{GET, "/users/:id/profile"},
+		{GET, "/files/static/info"},
+		{GET, "/files/:name/meta"},
+		{GET, "/files/*path"},
+		{GET, "/items/:id<int>/a"},
+		{GET, "/items/:slug/b"},
+		{PUT, "/items/:slug/a"},
+		{GET, "/docs/:name.html"},
+		{GET, "/docs/:name"},
+	}
+	for _, r := range routes {
+		pattern := r.pattern
+		router.AddRoute(r.method, pattern, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, pattern)
+		}))
+	}
+
+	cases := []struct {
+		method Method
+		path   string
+		code   int
+		body   string
+	}{
+		// static beats param.
+		{GET, "/users/new", 200, "/users/new"},
+		// static matches only for another method.
+		{DELETE, "/users/new", 200, "/users/:id"},
+		// static fails deeper.
+		{GET, "/users/new/profile", 200, "/users/:id/profile"},
+		// no branch serves the method.
+		{POST, "/users/new", 405, ""},
+		{GET, "/users/42/settings", 404, ""},
+		// param beats catch-all.
+		{GET, "/files/static/info", 200, "/files/static/info"},
+		{GET, "/files/static/meta", 200, "/files/:name/meta"},
+		{GET, "/files/static/other", 200, "/files/*path"},
+		{GET, "/files/a/meta", 200, "/files/:name/meta"},
+		{GET, "/files/a/meta/more", 200, "/files/*path"},
+		// constrained param beats unconstrained param.
+		{GET, "/items/42/a", 200, "/items/:id<int>/a"},
+		{GET, "/items/42/b", 200, "/items/:slug/b"},
+		{PUT, "/items/42/a", 200, "/items/:slug/a"},
+		{GET, "/items/abc/a", 405, ""},
+		// static text after param beats param at the end.
+		{GET, "/docs/index.html", 200, "/docs/:name.html"},
+		{GET, "/docs/index.txt", 200, "/docs/:name"},
+	}
+	for _, c := range cases {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(c.method.String(), c.path, nil)
+		router.ServeHTTP(w, req)
+		if got, exp := w.Code, c.code; got != exp {
+			t.Errorf("%s %s code not match. exp: %d, got: %d ",
+				c.method, c.path, exp, got)
+		}
+		if got, exp := w.Body.String(), c.body; c.code == 200 && got != exp {
+			t.Errorf("%s %s response not match. exp: %s, got: %s ",
+				c.method, c.path, exp, got)
+		}
+	}
+
+	allows := []struct {
+		path  string
+		allow string
+	}{
+		{"/users/new", "GET, HEAD, DELETE"},
+		{"/items/abc/a", "PUT"},
+		{"/items/42/a", "GET, HEAD, PUT"},
+	}
+	for _, c := range allows {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest("POST", c.path, nil))
+		if got, exp := w.Header().Get("Allow"), c.allow; got != exp {
+			t.Errorf("POST %s Allow not match. exp: %s, got: %s", c.path, exp, got)
+		}
+	}
+}
+
+type nopResponseWriter struct {
+	header http.Header
+}
+
+func (w *nopResponseWriter) Header() http.Header {
+	return w.header
+}
+
+func (w *nopResponseWriter) Write(b []byte) (int, error) {
+	return len(b), nil
+}
+
+func (w *nopResponseWriter) WriteHeader(int) {}
+
+func benchmarkRoute(b *testing.B, pattern, path string) {
+	router := NewRouter()
+	router.AddRoute(GET, "/users", defaultHandler)
+	router.AddRoute(GET, "/users/:id/posts/:post", defaultHandler)
+	router.AddRoute(GET, pattern, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	w := &nopResponseWriter{header: http.Header{}}
+	req := httptest.NewRequest("GET", path, nil)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		router.ServeHTTP(w, req)
+	}
+}
+
+// TestServeAllocs checks allocations of serving requests. The matched route
+// is saved in a context derived per request, see RouteOfReq, which costs the
+// context and the copy of the request by WithContext, even for static routes.
+func TestServeAllocs(t *testing.T) {
+	router := NewRouter()
+	nop := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	router.AddRoute(GET, "/users/new", nop)
+	router.AddRoute(GET, "/users/:id", nop)
+	router.AddRoute(GET, "/users/:id/posts/:post/comments/:comment", nop)
+	router.AddRoute(GET, "/a/:a/b/:b/c/:c/d/:d/e/:e", nop)
+	w := &nopResponseWriter{header: http.Header{}}
+
+	cases := []struct {
+		path   string
+		allocs float64
+	}{
+		{"/users/new", 2},
+		{"/users/42", 2},
+		{"/users/42/posts/7/comments/1", 2},
+		{"/a/1/b/2/c/3/d/4/e/5", 3},
+	}
+	for _, c := range cases {
+		req := httptest.NewRequest("GET", c.path, nil)
+		allocs := testing.AllocsPerRun(100, func() {
+			router.ServeHTTP(w, req)
+		})
+		if allocs > c.allocs {
+			t.Errorf("GET %s allocs not match. exp: %v, got: %v", c.path, c.allocs, allocs)
+		}
+	}
+}
+
+func BenchmarkStaticRoute(b *testing.B) {
+	benchmarkRoute(b, "/users/new", "/users/new")
+}
+
+func BenchmarkParamRoute(b *testing.B) {
+	benchmarkRoute(b, "/users/:id", "/users/42")
+}
+
+func BenchmarkParamsRoute(b *testing.B) {
+	benchmarkRoute(b, "/users/:id/posts/:post/comments/:comment", "/users/42/posts/7/comments/1")
+}
@@ -0,0 +1,280 @@
+/*
+ * Copyright 2017 Xuyuan Pang
+ * Author: Xuyuan Pang
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package hodor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+type paramsKeyType string
+
+const paramsKey paramsKeyType = "HodorParam"
+
+// ParamsOfReq returns the value of the named param of the matched route.
+func ParamsOfReq(r *http.Request, name string) (string, bool) {
+	return ParamsOfCtx(r.Context(), name)
+}
+
+// ParamsOfCtx returns the value of the named param of the matched route.
+func ParamsOfCtx(ctx context.Context, name string) (string, bool) {
+	if p, ok := ctx.Value(paramsKey).(Params); ok {
+		return p.Get(name)
+	}
+	return "", false
+}
+
+// RouteOfReq returns the matched route, for handlers and route filters. See
+// RequestRecorder for filters outside the router.
+func RouteOfReq(r *http.Request) (RouteInfo, bool) {
+	return RouteOfCtx(r.Context())
+}
+
+// RouteOfCtx returns the matched route, see RouteOfReq.
+func RouteOfCtx(ctx context.Context) (RouteInfo, bool) {
+	if ps, ok := ctx.Value(paramsKey).(*params); ok && ps.route != nil {
+		return ps.route.RouteInfo, true
+	}
+	return RouteInfo{}, false
+}
+
+// ParamListOfReq returns all params of the matched route.
+func ParamListOfReq(r *http.Request) Params {
+	return ParamListOfCtx(r.Context())
+}
+
+// ParamListOfCtx returns all params of the matched route.
+func ParamListOfCtx(ctx context.Context) Params {
+	if p, ok := ctx.Value(paramsKey).(Params); ok {
+		return p
+	}
+	return emptyParams
+}
+
+// Params are params of the matched route.
+type Params interface {
+	Get(string) (string, bool)
+	// Len returns the count of params.
+	Len() int
+	// At returns the name and value of the i-th param in path order.
+	At(i int) (name, value string)
+}
+
+var emptyParams Params = new(params)
+
+// ErrMissingParam is the error of ParamError if the param is missing.
+var ErrMissingParam = errors.New("missing param")
+
+// ParamError is returned by typed param accessors like ParamInt.
+type ParamError struct {
+	Name  string
+	Value string
+	// Type is the expected type of the value.
+	Type string
+	Err  error
+}
+
+func (e *ParamError) Error() string {
+	if e.Err == ErrMissingParam {
+		return "hodor: missing param " + e.Name
+	}
+	return fmt.Sprintf("hodor: param %s=%q is not a valid %s", e.Name, e.Value, e.Type)
+}
+
+// Unwrap returns the underlying error.
+func (e *ParamError) Unwrap() error {
+	return e.Err
+}
+
+func paramOfReq(r *http.Request, name, typ string) (string, error) {
+	value, ok := ParamsOfReq(r, name)
+	if !ok {
+		return "", &ParamError{Name: name, Type: typ, Err: ErrMissingParam}
+	}
+	return value, nil
+}
+
+// ParamInt returns the named param as an int.
+func ParamInt(r *http.Request, name string) (int, error) {
+	value, err := paramOfReq(r, name, "int")
+	if err != nil {
+		return 0, err
+	}
+	i, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, &ParamError{Name: name, Value: value, Type: "int", Err: err}
+	}
+	return i, nil
+}
+
+// ParamInt64 returns the named param as an int64.
+func ParamInt64(r *http.Request, name string) (int64, error) {
+	value, err := paramOfReq(r, name, "int64")
+	if err != nil {
+		return 0, err
+	}
+	i, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return 0, &ParamError{Name: name, Value: value, Type: "int64", Err: err}
+	}
+	return i, nil
+}
+
+// ParamBool returns the named param as a bool, accepting values supported
+// by strconv.ParseBool.
+func ParamBool(r *http.Request, name string) (bool, error) {
+	value, err := paramOfReq(r, name, "bool")
+	if err != nil {
+		return false, err
+	}
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, &ParamError{Name: name, Value: value, Type: "bool", Err: err}
+	}
+	return b, nil
+}
+
+var errInvalidUUID = errors.New("invalid UUID format")
+
+// ParamUUID returns the named param as a UUID in the canonical lowercase
+// form like "123e4567-e89b-12d3-a456-426655440000".
+func ParamUUID(r *http.Request, name string) (string, error) {
+	value, err := paramOfReq(r, name, "UUID")
+	if err != nil {
+		return "", err
+	}
+	if !isUUID(value) {
+		return "", &ParamError{Name: name, Value: value, Type: "UUID", Err: errInvalidUUID}
+	}
+	return strings.ToLower(value), nil
+}
+
+func isUUID(s string) bool {
+	if len(s) != 36 {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch i {
+		case 8, 13, 18, 23:
+			if c != '-' {
+				return false
+			}
+		default:
+			if !('0' <= c && c <= '9' || 'a' <= c && c <= 'f' || 'A' <= c && c <= 'F') {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+type param struct {
+	name  string
+	value string
+}
+
+// params is a slice-backed Params. NodeRouter matches routes with pooled
+// params, and passes a copy of them to handlers as the request context, see
+// detach, so that handlers outliving the request never see another one.
+type params struct {
+	context.Context
+	kvs   []param
+	route *route
+}
+
+func (ps *params) Value(key interface{}) interface{} {
+	if key == paramsKey {
+		return ps
+	}
+	return ps.Context.Value(key)
+}
+
+func (ps *params) Get(name string) (string, bool) {
+	for i := range ps.kvs {
+		if ps.kvs[i].name == name {
+			return ps.kvs[i].value, true
+		}
+	}
+	return "", false
+}
+
+func (ps *params) Len() int {
+	return len(ps.kvs)
+}
+
+func (ps *params) At(i int) (name, value string) {
+	return ps.kvs[i].name, ps.kvs[i].value
+}
+
+func (ps *params) add(name, value string) {
+	ps.kvs = append(ps.kvs, param{name: name, value: value})
+}
+
+// unescape decodes values matched in the escaped path.
+func (ps *params) unescape() {
+	for i := range ps.kvs {
+		if strings.IndexByte(ps.kvs[i].value, '%') == -1 {
+			continue
+		}
+		if v, err := url.PathUnescape(ps.kvs[i].value); err == nil {
+			ps.kvs[i].value = v
+		}
+	}
+}
+
+func (ps *params) truncate(n int) {
+	ps.kvs = ps.kvs[:n]
+}
+
+// inlineParams is the count of params held by detachedParams inline.
+const inlineParams = 4
+
+// detachedParams are params with their values, allocated at once.
+type detachedParams struct {
+	params
+	inline [inlineParams]param
+}
+
+// detach returns a copy of ps, not shared with the pool, as the request
+// context derived from ctx. It costs a single allocation for routes with up
+// to inlineParams params.
+func (ps *params) detach(ctx context.Context) *params {
+	if len(ps.kvs) == 0 {
+		return &params{Context: ctx, route: ps.route}
+	}
+	d := &detachedParams{params: params{Context: ctx, route: ps.route}}
+	if len(ps.kvs) <= len(d.inline) {
+		d.kvs = d.inline[:len(ps.kvs)]
+	} else {
+		d.kvs = make([]param, len(ps.kvs))
+	}
+	copy(d.kvs, ps.kvs)
+	return &d.params
+}
+
+func (ps *params) reset() {
+	ps.Context = nil
+	ps.kvs = ps.kvs[:0]
+	ps.route = nil
+}
@@ -0,0 +1,119 @@
+package hodor
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestParamList(t *testing.T) {
+	router := NewRouter()
+	router.AddRoute(GET, "/repos/:owner/:repo/*path", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ps := ParamListOfReq(r)
+		for i := 0; i < ps.Len(); i++ {
+			name, value := ps.At(i)
+			fmt.Fprintf(w, "%s=%s;", name, value)
+		}
+	}))
+	router.AddRoute(GET, "/", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, ParamListOfReq(r).Len())
+	}))
+
+	cases := []struct {
+		path string
+		body string
+	}{
+		{"/repos/alice/hodor/src/node.go", "owner=alice;repo=hodor;path=src/node.go;"},
+		{"/", "0"},
+	}
+	for _, c := range cases {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest("GET", c.path, nil))
+		if got, exp := w.Body.String(), c.body; got != exp {
+			t.Errorf("GET %s response not match. exp: %s, got: %s", c.path, exp, got)
+		}
+	}
+}
+
+func TestTypedParams(t *testing.T) {
+	router := NewRouter()
+	router.AddRoute(GET, "/:value", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		i, err := ParamInt(r, "value")
+		fmt.Fprintf(w, "%d,%v;", i, err)
+		i64, err := ParamInt64(r, "value")
+		fmt.Fprintf(w, "%d,%v;", i64, err)
+		b, err := ParamBool(r, "value")
+		fmt.Fprintf(w, "%v,%v;", b, err)
+		u, err := ParamUUID(r, "value")
+		fmt.Fprintf(w, "%s,%v;", u, err)
+		_, err = ParamInt(r, "missing")
+		fmt.Fprintf(w, "%v", err)
+	}))
+
+	missing := "hodor: missing param missing"
+	cases := []struct {
+		path string
+		body string
+	}{
+		{"/42", `42,<nil>;42,<nil>;false,hodor: param value="42" is not a valid bool;` +
+			`,hodor: param value="42" is not a valid UUID;` + missing},
+		{"/1", `1,<nil>;1,<nil>;true,<nil>;,hodor: param value="1" is not a valid UUID;` + missing},
+		{"/123E4567-E89B-12D3-A456-426655440000", `0,hodor: param value="123E4567-E89B-12D3-A456-426655440000" is not a valid int;` +
+			`0,hodor: param value="123E4567-E89B-12D3-A456-426655440000" is not a valid int64;` +
+			`false,hodor: param value="123E4567-E89B-12D3-A456-426655440000" is not a valid bool;` +
+			`123e4567-e89b-12d3-a456-426655440000,<nil>;` + missing},
+	}
+	for _, c := range cases {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest("GET", c.path, nil))
+		if got, exp := w.Body.String(), c.body; got != exp {
+			t.Errorf("GET %s response not match.\nexp: %s\ngot: %s", c.path, exp, got)
+		}
+	}
+}
+
+func TestRouteOfReq(t *testing.T) {
+	router := NewRouter()
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if rt, ok := RouteOfReq(r); ok {
+			fmt.Fprintf(w, "%s %s %s", rt.Method, rt.Pattern, rt.Name)
+		}
+	})
+	router.AddRoute(GET, "/users/:id", handler, nameOption("user.show"))
+	router.AddRoute(GET, "/users", handler)
+
+	cases := []struct {
+		method Method
+		path   string
+		body   string
+	}{
+		{GET, "/users/42", "GET /users/:id user.show"},
+		{GET, "/users", "GET /users "},
+	}
+	for _, c := range cases {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(c.method.String(), c.path, nil))
+		if got, exp := w.Body.String(), c.body; got != exp {
+			t.Errorf("%s %s response not match. exp: %s, got: %s", c.method, c.path, exp, got)
+		}
+	}
+}
+
+func TestParamsRetained(t *testing.T) {
+	router := NewRouter()
+	var reqs []*http.Request
+	router.AddRoute(GET, "/users/:id", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		reqs = append(reqs, r)
+	}))
+
+	for _, id := range []string{"first", "second"} {
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/users/"+id, nil))
+	}
+	if got, _ := ParamsOfReq(reqs[0], "id"); got != "first" {
+		t.Errorf("params retained by the handler should be kept. exp: first, got: %s", got)
+	}
+	if rt, ok := RouteOfReq(reqs[0]); !ok || rt.Pattern != "/users/:id" {
+		t.Errorf("route retained by the handler should be kept, got: %v %v", rt, ok)
+	}
+}
@@ -0,0 +1,126 @@
+/*
+ * Copyright 2016 Xuyuan Pang
+ * Author: Xuyuan Pang
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package hodor
+
+import (
+	"context"
+	"strings"
+)
+
+const recorderKey paramsKeyType = "HodorRecorder"
+
+// RequestRecorder records the route matched by NodeRouter and the request ID
+// saved by RequestIDFilter, for filters outside them, like logging, metrics
+// and tracing filters added to Hodor. They can't get them from the request
+// context, as the contexts holding them are derived inside. A request has a
+// single recorder shared by all filters, see WithRequestRecorder.
+type RequestRecorder struct {
+	route *route
+	// prefix is the pattern of mounts route is under, and exact reports
+	// whether the last one matched its prefix exactly.
+	prefix string
+	exact  bool
+	// mountPrefix and mountExact are prefix and exact of routes of the
+	// handler mounted by route.
+	mountPrefix string
+	mountExact  bool
+	requestID   string
+	// waiters are called once the request ID is recorded, or NodeRouter
+	// serves the request, see waitRequestID.
+	waiters []func(id string)
+}
+
+// WithRequestRecorder returns a copy of ctx with a new RequestRecorder, or
+// ctx itself if it has one already, and the recorder.
+func WithRequestRecorder(ctx context.Context) (context.Context, *RequestRecorder) {
+	if rec := requestRecorderOfCtx(ctx); rec != nil {
+		return ctx, rec
+	}
+	rec := new(RequestRecorder)
+	return context.WithValue(ctx, recorderKey, rec), rec
+}
+
+func requestRecorderOfCtx(ctx context.Context) *RequestRecorder {
+	rec, _ := ctx.Value(recorderKey).(*RequestRecorder)
+	return rec
+}
+
+// recordRoute records the matched route rt.
+func (rec *RequestRecorder) recordRoute(rt *route) {
+	rec.route = rt
+	rec.prefix, rec.exact = rec.mountPrefix, rec.mountExact
+}
+
+// recordMount records the mount route pattern the request is passed under,
+// see Route.Mount. If sub is false, the prefix is matched exactly.
+func (rec *RequestRecorder) recordMount(pattern string, sub bool) {
+	if sub {
+		pattern = strings.TrimSuffix(pattern, mountSuffix)
+	}
+	rec.mountPrefix, rec.mountExact = rec.prefix+pattern, !sub
+}
+
+// recordRequestID records the request ID and releases the waiters.
+func (rec *RequestRecorder) recordRequestID(id string) {
+	rec.requestID = id
+	rec.release()
+}
+
+// release calls the waiters with the request ID recorded so far. NodeRouter
+// releases them when it starts serving the request, as filters outside it
+// have all been passed then.
+func (rec *RequestRecorder) release() {
+	for _, wait := range rec.waiters {
+		wait(rec.requestID)
+	}
+	rec.waiters = nil
+}
+
+// waitRequestID adds f called with the request ID once it's recorded, or
+// with the one recorded so far once NodeRouter serves the request.
+func (rec *RequestRecorder) waitRequestID(f func(id string)) {
+	rec.waiters = append(rec.waiters, f)
+}
+
+// Route returns the route recorded, or false if no route matched. It should
+// be called after the request is served.
+func (rec *RequestRecorder) Route() (RouteInfo, bool) {
+	if rec.route == nil {
+		return RouteInfo{}, false
+	}
+	return rec.route.RouteInfo, true
+}
+
+// Pattern returns the full pattern of the route recorded, with prefixes of
+// the mounts it's under, like "/admin/users/:id" for the route "/users/:id"
+// of a router mounted at "/admin". It's empty if no route matched.
+func (rec *RequestRecorder) Pattern() string {
+	if rec.route == nil {
+		return ""
+	}
+	if rec.exact && rec.route.Pattern == "/" {
+		return rec.prefix
+	}
+	return rec.prefix + rec.route.Pattern
+}
+
+// RequestID returns the request ID recorded, or an empty string if
+// RequestIDFilter isn't added after the filter getting the recorder.
+func (rec *RequestRecorder) RequestID() string {
+	return rec.requestID
+}
@@ -0,0 +1,52 @@
+package hodor
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRequestRecorder(t *testing.T) {
+	h := NewHodor(NewRouter())
+	h.Route().Get().Pattern("/users/:id").Name("user.show").Handler(defaultHandler)
+	h.Route().Get().Pattern("/users").Handler(defaultHandler)
+
+	var recs [2]*RequestRecorder
+	recorder := func(i int) Filter {
+		return FilterFunc(func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				ctx, rec := WithRequestRecorder(r.Context())
+				recs[i] = rec
+				next.ServeHTTP(w, r.WithContext(ctx))
+			})
+		})
+	}
+	h.SetFilters(recorder(0), recorder(1), RequestIDFilter(RequestIDOptions{}))
+
+	cases := []struct {
+		path    string
+		pattern string
+	}{
+		{"/users/42", "/users/:id"},
+		{"/users", "/users"},
+		{"/missing", ""},
+	}
+	for _, c := range cases {
+		req := httptest.NewRequest("GET", c.path, nil)
+		req.Header.Set("X-Request-ID", "req"+c.path)
+		h.ServeHTTP(httptest.NewRecorder(), req)
+		if recs[0] != recs[1] {
+			t.Errorf("GET %s recorder should be shared by filters", c.path)
+		}
+		rec := recs[0]
+		if got := rec.Pattern(); got != c.pattern {
+			t.Errorf("GET %s route recorded not match. exp: %s, got: %s", c.path, c.pattern, got)
+		}
+		if rt, ok := rec.Route(); ok != (c.pattern != "") || rt.Pattern != c.pattern {
+			t.Errorf("GET %s route info not match. exp: %s, got: %v %v", c.path, c.pattern, rt, ok)
+		}
+		if got := rec.RequestID(); got != "req"+c.path {
+			t.Errorf("GET %s request ID recorded not match. exp: %s, got: %s", c.path, "req"+c.path, got)
+		}
+	}
+}
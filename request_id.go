@@ -0,0 +1,130 @@
+/*
+ * Copyright 2016 Xuyuan Pang
+ * Author: Xuyuan Pang
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package hodor
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"sync/atomic"
+)
+
+const requestIDKey paramsKeyType = "HodorRequestID"
+
+// requestIDFilters is set once RequestIDFilter is called, so that LogFilter
+// doesn't wait for request IDs if it's never used.
+var requestIDFilters int32
+
+// RequestIDOptions configures RequestIDFilter.
+type RequestIDOptions struct {
+	// Header is the request and response header of request IDs,
+	// "X-Request-ID" if empty.
+	Header string
+	// MaxLength is the maximum length of incoming request IDs, 64 if zero.
+	MaxLength int
+	// Generate generates request IDs, random UUIDs if nil.
+	Generate func() string
+}
+
+// RequestIDFilter new filter saving the request ID in the request context,
+// see RequestIDOfReq, and echoing it in the response. Incoming request IDs
+// are kept if they are valid, made up of ASCII letters, digits and "-_.:+/=",
+// otherwise a new one is generated. It records the request ID in the
+// RequestRecorder of the request as well, so LogFilter and AccessLogFilter
+// log it whether they are added before or after it.
+func RequestIDFilter(opts RequestIDOptions) FilterFunc {
+	header := opts.Header
+	if header == "" {
+		header = "X-Request-ID"
+	}
+	maxLength := opts.MaxLength
+	if maxLength == 0 {
+		maxLength = 64
+	}
+	generate := opts.Generate
+	if generate == nil {
+		generate = newUUID
+	}
+	atomic.StoreInt32(&requestIDFilters, 1)
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(
+			func(w http.ResponseWriter, r *http.Request) {
+				id := r.Header.Get(header)
+				if !validRequestID(id, maxLength) {
+					id = generate()
+				}
+				w.Header().Set(header, id)
+				if rec := requestRecorderOfCtx(r.Context()); rec != nil {
+					rec.recordRequestID(id)
+				}
+				ctx := context.WithValue(r.Context(), requestIDKey, id)
+				next.ServeHTTP(w, r.WithContext(ctx))
+			})
+	}
+}
+
+// RequestIDOfReq returns the request ID saved by RequestIDFilter, or an
+// empty string if there is none.
+func RequestIDOfReq(r *http.Request) string {
+	return RequestIDOfCtx(r.Context())
+}
+
+// RequestIDOfCtx returns the request ID saved by RequestIDFilter, see
+// RequestIDOfReq.
+func RequestIDOfCtx(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey).(string)
+	return id
+}
+
+func validRequestID(id string, maxLength int) bool {
+	if id == "" || len(id) > maxLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		switch c := id[i]; {
+		case 'a' <= c && c <= 'z', 'A' <= c && c <= 'Z', '0' <= c && c <= '9':
+		case c == '-', c == '_', c == '.', c == ':', c == '+', c == '/', c == '=':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// newUUID returns a random version 4 UUID.
+func newUUID() string {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		panic(err)
+	}
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	var s [36]byte
+	hex.Encode(s[0:8], b[0:4])
+	s[8] = '-'
+	hex.Encode(s[9:13], b[4:6])
+	s[13] = '-'
+	hex.Encode(s[14:18], b[6:8])
+	s[18] = '-'
+	hex.Encode(s[19:23], b[8:10])
+	s[23] = '-'
+	hex.Encode(s[24:], b[10:])
+	return string(s[:])
+}
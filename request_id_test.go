@@ -0,0 +1,138 @@
+package hodor
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func TestRequestIDFilter(t *testing.T) {
+	var got string
+	handler := RequestIDFilter(RequestIDOptions{MaxLength: 40}).Do(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			got = RequestIDOfReq(r)
+		}))
+
+	cases := []struct {
+		incoming string
+		kept     bool
+	}{
+		{"abc-123_DEF.456:7+8/9=", true},
+		{"", false},
+		{"has space", false},
+		{"<script>", false},
+		{strings.Repeat("a", 41), false},
+	}
+	for _, c := range cases {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest("GET", "/", nil)
+		if c.incoming != "" {
+			req.Header.Set("X-Request-ID", c.incoming)
+		}
+		handler.ServeHTTP(w, req)
+		if got == "" {
+			t.Errorf("request ID of %q should be saved", c.incoming)
+		}
+		if c.kept && got != c.incoming {
+			t.Errorf("request ID not match. exp: %q, got: %q", c.incoming, got)
+		}
+		if !c.kept && (got == c.incoming || !isUUID(got)) {
+			t.Errorf("request ID of %q should be generated, got: %q", c.incoming, got)
+		}
+		if echo := w.Header().Get("X-Request-ID"); echo != got {
+			t.Errorf("echoed request ID not match. exp: %q, got: %q", got, echo)
+		}
+	}
+}
+
+func TestRequestIDFilterOptions(t *testing.T) {
+	h := NewHodor(NewRouter())
+	h.Route().Get().Pattern("/").Handler(defaultHandler)
+	var lines []string
+	logger := LogFunc(func(format string, args ...interface{}) {
+		lines = append(lines, fmt.Sprintf(format, args...))
+	})
+	h.SetFilters(
+		RequestIDFilter(RequestIDOptions{
+			Header:   "X-Trace",
+			Generate: func() string { return "generated" },
+		}),
+		LogFilter(logger),
+	)
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+	if got, exp := w.Header().Get("X-Trace"), "generated"; got != exp {
+		t.Errorf("request ID not match. exp: %q, got: %q", exp, got)
+	}
+	if len(lines) != 2 {
+		t.Fatalf("log lines not match. exp: 2, got: %d", len(lines))
+	}
+	for _, line := range lines {
+		if !strings.HasPrefix(line, "[generated] ") {
+			t.Errorf("log line should be prefixed by request ID, got: %q", line)
+		}
+	}
+}
+
+func TestLogFilterBeforeRequestIDFilter(t *testing.T) {
+	h := NewHodor(NewRouter())
+	var lines []string
+	h.Route().Get().Pattern("/").HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lines = append(lines, "handler")
+		w.WriteHeader(http.StatusOK)
+	})
+	h.Route().Get().Pattern("/noid").HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lines = append(lines, "handler")
+		w.WriteHeader(http.StatusOK)
+	})
+	logger := LogFunc(func(format string, args ...interface{}) {
+		lines = append(lines, fmt.Sprintf(format, args...))
+	})
+	h.SetFilters(
+		LogFilter(logger),
+		RequestIDFilter(RequestIDOptions{Generate: func() string { return "generated" }}),
+	)
+
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+	if len(lines) != 3 {
+		t.Fatalf("log lines not match. exp: 3, got: %q", lines)
+	}
+	if !strings.HasPrefix(lines[0], "[generated] GET / ") || lines[1] != "handler" ||
+		!strings.HasPrefix(lines[2], "[generated] 200 OK ") {
+		t.Errorf("request line should be logged with the request ID before the handler, got: %q", lines)
+	}
+
+	// RequestIDFilter is used by another Hodor, the request line is logged
+	// once the router serves the request.
+	lines = nil
+	h.SetFilters(LogFilter(logger))
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/noid", nil))
+	if len(lines) != 3 || !strings.HasPrefix(lines[0], "GET /noid ") || lines[1] != "handler" ||
+		!strings.HasPrefix(lines[2], "200 OK ") {
+		t.Errorf("request line without request ID should be logged before the handler, got: %q", lines)
+	}
+}
+
+func TestLogFilterWithoutRequestIDFilter(t *testing.T) {
+	filters := atomic.SwapInt32(&requestIDFilters, 0)
+	defer atomic.StoreInt32(&requestIDFilters, filters)
+
+	var lines []string
+	req := httptest.NewRequest("GET", "/", nil)
+	handler := LogFilter(LogFunc(func(format string, args ...interface{}) {
+		lines = append(lines, fmt.Sprintf(format, args...))
+	})).Do(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r != req {
+			t.Error("request should be passed as is")
+		}
+		lines = append(lines, "handler")
+	}))
+	handler.ServeHTTP(NewResponseWriter(httptest.NewRecorder()), req)
+	if len(lines) != 3 || !strings.HasPrefix(lines[0], "GET / ") || lines[1] != "handler" {
+		t.Errorf("request line should be logged before the handler, got: %q", lines)
+	}
+}
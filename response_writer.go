@@ -27,6 +27,7 @@ import (
 	"io"
 	"net"
 	"net/http"
+	"strconv"
 )
 
 // ResponseWriter is a wrapper around http.ResponseWriter that provides extra information about
@@ -136,3 +137,98 @@ func (rw *responseWriter) Flush() {
 		flusher.Flush()
 	}
 }
+
+// headResponseWriter discards the response body of HEAD requests served by
+// GET handlers. Headers are delayed until the handler finishes, so that
+// Content-Length can be set to the size of the discarded body.
+type headResponseWriter struct {
+	ResponseWriter
+	status int
+	size   int
+}
+
+func newHeadResponseWriter(w http.ResponseWriter) *headResponseWriter {
+	rw, ok := w.(ResponseWriter)
+	if !ok {
+		rw = NewResponseWriter(w)
+	}
+	return &headResponseWriter{ResponseWriter: rw}
+}
+
+func (hw *headResponseWriter) WriteHeader(s int) {
+	if hw.status == 0 {
+		hw.status = s
+	}
+}
+
+func (hw *headResponseWriter) Write(b []byte) (int, error) {
+	if !hw.Written() {
+		hw.WriteHeader(http.StatusOK)
+	}
+	hw.size += len(b)
+	return len(b), nil
+}
+
+func (hw *headResponseWriter) WriteString(s string) (int, error) {
+	if !hw.Written() {
+		hw.WriteHeader(http.StatusOK)
+	}
+	hw.size += len(s)
+	return len(s), nil
+}
+
+func (hw *headResponseWriter) Status() int {
+	return hw.status
+}
+
+// Size returns the size of the body which would have been sent.
+func (hw *headResponseWriter) Size() int {
+	return hw.size
+}
+
+func (hw *headResponseWriter) Written() bool {
+	return hw.status != 0
+}
+
+// Flush writes the delayed headers without Content-Length, as the body may
+// go on after flushing, like the chunked body of GET requests.
+func (hw *headResponseWriter) Flush() {
+	if !hw.Written() {
+		hw.WriteHeader(http.StatusOK)
+	}
+	hw.writeHeader(false)
+	hw.ResponseWriter.Flush()
+}
+
+func (hw *headResponseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hijacker, ok := hw.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, fmt.Errorf("the ResponseWriter doesn't support the Hijacker interface")
+	}
+	return hijacker.Hijack()
+}
+
+func (hw *headResponseWriter) CloseNotify() <-chan bool {
+	return hw.ResponseWriter.(http.CloseNotifier).CloseNotify()
+}
+
+// finish writes the delayed headers to the underlying ResponseWriter.
+func (hw *headResponseWriter) finish() {
+	hw.writeHeader(true)
+}
+
+// writeHeader writes the delayed headers, with Content-Length set to the
+// size of the discarded body if contentLength is true.
+func (hw *headResponseWriter) writeHeader(contentLength bool) {
+	if hw.status == 0 || hw.ResponseWriter.Written() {
+		return
+	}
+	if contentLength && bodyAllowed(hw.status) && hw.Header().Get("Content-Length") == "" {
+		hw.Header().Set("Content-Length", strconv.Itoa(hw.size))
+	}
+	hw.ResponseWriter.WriteHeader(hw.status)
+}
+
+func bodyAllowed(status int) bool {
+	return status >= 200 && status != http.StatusNoContent && status != http.StatusNotModified
+}
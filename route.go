@@ -91,6 +91,47 @@ func (ms Route) Patch() PatternSetter {
 	return ms.Method(PATCH)
 }
 
+// Any short for Method(ANY), matching requests of any method, unless the
+// pattern has a route of the method.
+func (ms Route) Any() PatternSetter {
+	return ms.Method(ANY)
+}
+
+// Match adds the route for each of methods, e.g. Match(WebDAVMethods...).
+func (ms Route) Match(methods ...Method) PatternSetter {
+	return func(pattern string) HandlerSetter {
+		return func(handler http.Handler, filters ...Filter) {
+			for _, method := range methods {
+				ms.Method(method).Pattern(pattern)(handler, filters...)
+			}
+		}
+	}
+}
+
+// Host restricts routes to requests of the host pattern, like
+// "api.example.com", ":tenant.example.com" or "*.example.com". Host params
+// are available as Params like path params. The port of requests is ignored.
+// Routes without host serve requests not matching any host.
+func (ms Route) Host(host string) Route {
+	return func(method Method) PatternSetter {
+		return func(pattern string) HandlerSetter {
+			return ms.Method(method).Pattern(pattern).Filters(hostOption(host))
+		}
+	}
+}
+
+// Mount sends requests of every method to the prefix, and every path under
+// it, to handler, like another Hodor, a NodeRouter or an http.FileServer.
+// The prefix is stripped from URL.Path and URL.RawPath of requests passed to
+// handler, and the original URL is available by OriginalURLOfReq.
+func (ms Route) Mount(prefix string, handler http.Handler) {
+	prefix, sub := mountPattern(prefix)
+	if prefix != "" {
+		ms.Any().Pattern(prefix).Handler(&mountHandler{handler: handler})
+	}
+	ms.Any().Pattern(sub).Handler(&mountHandler{handler: handler, sub: true})
+}
+
 // Group creates a Group with root
 func (ms Route) Group(root string) Grouper {
 	return func(fn func(Route), fs ...Filter) {
@@ -138,6 +179,55 @@ func (hs HandlerSetter) FilterFunc(f func(http.Handler) http.Handler) HandlerSet
 	return hs.Filters(FilterFunc(f))
 }
 
+// Name names the route, so that its URL can be built by name later.
+func (hs HandlerSetter) Name(name string) HandlerSetter {
+	return hs.Filters(nameOption(name))
+}
+
+// When restricts the route to requests matching all of matchers, so that
+// several handlers may share the same method and pattern.
+func (hs HandlerSetter) When(matchers ...Matcher) HandlerSetter {
+	return hs.Filters(matchOption(matchers))
+}
+
+// RouteOption configures a route. Options are passed to Router.AddRoute along
+// with filters, and routers not supporting them treat them as empty filters.
+type RouteOption interface {
+	Filter
+	apply(*routeConfig)
+}
+
+// routeConfig is the configuration of a route collected from RouteOptions.
+type routeConfig struct {
+	name     string
+	host     string
+	matchers []Matcher
+}
+
+// splitOptions separates RouteOptions from filters.
+func splitOptions(filters []Filter) (*routeConfig, []Filter) {
+	cfg := new(routeConfig)
+	fs := make([]Filter, 0, len(filters))
+	for _, f := range filters {
+		if opt, ok := f.(RouteOption); ok {
+			opt.apply(cfg)
+			continue
+		}
+		fs = append(fs, f)
+	}
+	return cfg, fs
+}
+
+type nameOption string
+
+func (o nameOption) Do(next http.Handler) http.Handler {
+	return next
+}
+
+func (o nameOption) apply(cfg *routeConfig) {
+	cfg.name = string(o)
+}
+
 // Grouper is to add routes grouply.
 type Grouper func(func(Route), ...Filter)
 
@@ -157,3 +247,18 @@ func (g Grouper) Filters(filters ...Filter) Grouper {
 func (g Grouper) FilterFunc(f func(http.Handler) http.Handler) Grouper {
 	return g.Filters(FilterFunc(f))
 }
+
+// WithHost restricts a route to requests of the host pattern, see Route.Host.
+func WithHost(host string) RouteOption {
+	return hostOption(host)
+}
+
+type hostOption string
+
+func (o hostOption) Do(next http.Handler) http.Handler {
+	return next
+}
+
+func (o hostOption) apply(cfg *routeConfig) {
+	cfg.host = lowerHost(string(o))
+}
@@ -19,13 +19,21 @@ package hodor
 
 import (
 	"context"
+	"fmt"
 	"net/http"
+	"net/url"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
 )
 
 // Method is HTTP method.
 type Method string
 
-// available methods.
+// available methods. Other methods, like WebDAV methods, are supported as
+// well, and listed in the Allow header after these ones.
 const (
 	OPTIONS Method = "OPTIONS"
 	GET            = "GET"
@@ -38,7 +46,23 @@ const (
 	PATCH          = "PATCH"
 )
 
-// Methods is a list of all valid methods.
+// WebDAV methods, see RFC 4918.
+const (
+	PROPFIND  Method = "PROPFIND"
+	PROPPATCH        = "PROPPATCH"
+	MKCOL            = "MKCOL"
+	COPY             = "COPY"
+	MOVE             = "MOVE"
+	LOCK             = "LOCK"
+	UNLOCK           = "UNLOCK"
+)
+
+// ANY is the method of routes handling requests of any method without a
+// route of their own method, see Route.Any.
+const ANY Method = "*"
+
+// Methods is a list of common methods, which ANY routes are listed as in
+// the Allow header.
 var Methods = []Method{
 	OPTIONS,
 	GET,
@@ -55,17 +79,138 @@ func (m Method) String() string {
 	return string(m)
 }
 
+// WebDAVMethods is a list of WebDAV methods, e.g. for Route.Match.
+var WebDAVMethods = []Method{
+	PROPFIND,
+	PROPPATCH,
+	MKCOL,
+	COPY,
+	MOVE,
+	LOCK,
+	UNLOCK,
+}
+
+// sortMethods sorts methods in the order of Methods, other methods
+// alphabetically after them, and ANY at last.
+func sortMethods(methods []Method) {
+	rank := func(m Method) int {
+		if m == ANY {
+			return len(Methods) + 1
+		}
+		for i, method := range Methods {
+			if method == m {
+				return i
+			}
+		}
+		return len(Methods)
+	}
+	sort.Slice(methods, func(i, j int) bool {
+		ri, rj := rank(methods[i]), rank(methods[j])
+		if ri != rj {
+			return ri < rj
+		}
+		return methods[i] < methods[j]
+	})
+}
+
+// validMethod reports whether method is ANY or a token, see RFC 7230.
+func validMethod(method Method) bool {
+	if method == ANY {
+		return true
+	}
+	if method == "" {
+		return false
+	}
+	for i := 0; i < len(method); i++ {
+		c := method[i]
+		if c <= ' ' || c >= 0x7f || strings.IndexByte("\"(),/:;<=>?@[\\]{}", c) >= 0 {
+			return false
+		}
+	}
+	return true
+}
+
+func hasMethod(methods []Method, method Method) bool {
+	for _, m := range methods {
+		if m == method {
+			return true
+		}
+	}
+	return false
+}
+
+func joinMethods(methods []Method) string {
+	s := make([]string, len(methods))
+	for i, method := range methods {
+		s[i] = method.String()
+	}
+	return strings.Join(s, ", ")
+}
+
+const allowedMethodsKey paramsKeyType = "HodorAllowedMethods"
+
+// AllowedMethodsOfReq returns methods allowed for the requested path.
+// It's only available in Handler405 of NodeRouter.
+func AllowedMethodsOfReq(r *http.Request) []Method {
+	return AllowedMethodsOfCtx(r.Context())
+}
+
+// AllowedMethodsOfCtx returns methods allowed for the requested path.
+// It's only available in Handler405 of NodeRouter.
+func AllowedMethodsOfCtx(ctx context.Context) []Method {
+	methods, _ := ctx.Value(allowedMethodsKey).([]Method)
+	return methods
+}
+
 // Router interface
 type Router interface {
 	http.Handler
 	AddRoute(method Method, pattern string, handler http.Handler, filters ...Filter)
 }
 
-// NodeRouter struct
+// MethodLister is implemented by routers listing methods of routes matching
+// the host and the path of requests, whatever their methods.
+type MethodLister interface {
+	AllowedMethods(r *http.Request) []Method
+}
+
+// NodeRouter struct. Routes can be added and removed while serving requests.
 type NodeRouter struct {
-	root       *node
+	mu         sync.Mutex
+	tree       atomic.Value // *tree
+	pool       sync.Pool
 	Handler404 http.Handler
+	// Handler405 is called with the Allow header already set. The allowed
+	// methods are also available by AllowedMethodsOfReq.
 	Handler405 http.Handler
+	// Handler406 is called if routes of the path and method exist, but none
+	// of them accepts the request by MatchAccept.
+	Handler406 http.Handler
+	// Handler415 is called if routes of the path and method exist, but none
+	// of them accepts the request by MatchContentType.
+	Handler415 http.Handler
+	// HandleOPTIONS enables replying OPTIONS requests with the Allow header
+	// automatically, if there is no OPTIONS handler for the path.
+	HandleOPTIONS bool
+	// RedirectTrailingSlash enables redirecting to the path with or without
+	// the trailing slash, if only that one has a route.
+	RedirectTrailingSlash bool
+	// RedirectFixedPath enables redirecting to the cleaned path, with
+	// superfluous elements like ../ or // removed, if it has a route.
+	RedirectFixedPath bool
+	// RedirectCaseInsensitive enables redirecting to the path matching a
+	// route when static text is compared ignoring case.
+	RedirectCaseInsensitive bool
+	// CaseInsensitive enables matching static text of patterns ignoring
+	// ASCII case, so that "/Users" matches "/users". Params keep the case of
+	// the request.
+	CaseInsensitive bool
+	// UseEscapedPath enables matching URL.EscapedPath instead of the decoded
+	// URL.Path, so that an escaped slash like "%2F" doesn't split a param.
+	// Param values are decoded after matching, while param constraints are
+	// checked against escaped values. Static text of patterns should be in
+	// the escaped form then.
+	UseEscapedPath bool
 }
 
 func errHandler(status int) http.HandlerFunc {
@@ -74,34 +219,412 @@ func errHandler(status int) http.HandlerFunc {
 	}
 }
 
+// tree is an immutable snapshot of routes. NodeRouter replaces it as a whole
+// when routes change, so that matching needs no lock.
+type tree struct {
+	// root holds routes without host, serving requests of any host.
+	root *node
+	// hosts are ordered by hostTree.rank.
+	hosts []*hostTree
+	names map[string]*route
+	// maxParams is the maximum count of params of routes.
+	maxParams int
+}
+
+// match returns the node serving the request of host and path. Routes of
+// matching hosts are tried first, then routes without host.
+func (t *tree) match(l *lookup, host, path string) *node {
+	if len(t.hosts) > 0 {
+		mark := l.params.Len()
+		for _, ht := range t.hosts {
+			if ht.match(host, l.params) {
+				if n := ht.root.match(l, path); n != nil {
+					return n
+				}
+			}
+			l.params.truncate(mark)
+		}
+	}
+	return t.root.match(l, path)
+}
+
+// allowedMethods returns methods of all routes matching host and path in a
+// stable order. If fold is true, static text is compared ignoring case.
+func (t *tree) allowedMethods(host, path string, fold bool) []Method {
+	l := &lookup{params: new(params), allowed: map[Method]bool{}, fold: fold}
+	t.match(l, host, path)
+	methods := make([]Method, 0, len(l.allowed))
+	for method := range l.allowed {
+		methods = append(methods, method)
+	}
+	sortMethods(methods)
+	return methods
+}
+
+// hostRoot returns the root of routes of host.
+func (t *tree) hostRoot(host string) *node {
+	if host == "" {
+		return t.root
+	}
+	for _, ht := range t.hosts {
+		if ht.pattern == host {
+			return ht.root
+		}
+	}
+	return nil
+}
+
+// withRoot returns a copy of t, with root as the root of routes of host.
+func (t *tree) withRoot(host string, root *node) (*tree, error) {
+	c := *t
+	if host == "" {
+		c.root = root
+		return &c, nil
+	}
+	c.hosts = make([]*hostTree, 0, len(t.hosts)+1)
+	for _, ht := range t.hosts {
+		if ht.pattern == host {
+			ht = &hostTree{pattern: ht.pattern, labels: ht.labels, root: root}
+		}
+		c.hosts = append(c.hosts, ht)
+	}
+	if t.hostRoot(host) != nil {
+		return &c, nil
+	}
+	labels, err := parseHost(host)
+	if err != nil {
+		return nil, err
+	}
+	ht := &hostTree{pattern: host, labels: labels, root: root}
+	i := len(c.hosts)
+	for i > 0 && c.hosts[i-1].rank() > ht.rank() {
+		i--
+	}
+	c.hosts = append(c.hosts, nil)
+	copy(c.hosts[i+1:], c.hosts[i:])
+	c.hosts[i] = ht
+	return &c, nil
+}
+
+// walk calls fn for routes without host, and then routes of every host.
+func (t *tree) walk(fn func(*route) error) error {
+	if err := t.root.walk(fn); err != nil {
+		return err
+	}
+	for _, ht := range t.hosts {
+		if err := ht.root.walk(fn); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // NewRouter creates a new router
 func NewRouter() *NodeRouter {
-	return &NodeRouter{
-		root:       newNode(nil, ""),
+	nr := &NodeRouter{
 		Handler404: errHandler(http.StatusNotFound),
 		Handler405: errHandler(http.StatusMethodNotAllowed),
+		Handler406: errHandler(http.StatusNotAcceptable),
+		Handler415: errHandler(http.StatusUnsupportedMediaType),
+	}
+	nr.tree.Store(&tree{
+		root:  newNode(""),
+		names: map[string]*route{},
+	})
+	nr.pool.New = func() interface{} {
+		return &params{kvs: make([]param, 0, nr.load().maxParams)}
+	}
+	return nr
+}
+
+func (nr *NodeRouter) load() *tree {
+	return nr.tree.Load().(*tree)
+}
+
+// target returns the host and the path of r to match.
+func (nr *NodeRouter) target(t *tree, r *http.Request) (host, p string) {
+	if len(t.hosts) > 0 {
+		host = canonicalHost(r.Host)
+	}
+	p = r.URL.Path
+	if nr.UseEscapedPath {
+		p = r.URL.EscapedPath()
 	}
+	return host, p
+}
+
+// AllowedMethods implements MethodLister interface.
+func (nr *NodeRouter) AllowedMethods(r *http.Request) []Method {
+	t := nr.load()
+	host, p := nr.target(t, r)
+	return t.allowedMethods(host, p, nr.CaseInsensitive)
 }
 
 func (nr *NodeRouter) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-	p, h, err := nr.root.match(background, Method(r.Method), r.URL.Path)
-	switch err {
-	case errNotFound:
+	rec := requestRecorderOfCtx(r.Context())
+	if rec != nil {
+		rec.release()
+	}
+	t := nr.load()
+	host, p := nr.target(t, r)
+	method := Method(r.Method)
+	ps := nr.pool.Get().(*params)
+	l := lookup{method: method, params: ps, req: r, fold: nr.CaseInsensitive}
+	n := t.match(&l, host, p)
+	if n != nil {
+		head := n.headFallback(method)
+		rt := l.route
+		ps.route = rt
+		if rec != nil {
+			rec.recordRoute(rt)
+		}
+		if nr.UseEscapedPath {
+			ps.unescape()
+		}
+		r = r.WithContext(ps.detach(r.Context()))
+		ps.reset()
+		nr.pool.Put(ps)
+		if head {
+			hw := newHeadResponseWriter(w)
+			rt.handler.ServeHTTP(hw, r)
+			hw.finish()
+		} else {
+			rt.handler.ServeHTTP(w, r)
+		}
+		return
+	}
+	ps.reset()
+	nr.pool.Put(ps)
+
+	// Routes of the path and method rejecting the request by their matchers
+	// take precedence over routes of other methods.
+	switch {
+	case l.status == http.StatusNotAcceptable:
+		nr.Handler406.ServeHTTP(w, r)
+	case l.status == http.StatusUnsupportedMediaType:
+		nr.Handler415.ServeHTTP(w, r)
+	case l.status != 0:
 		nr.Handler404.ServeHTTP(w, r)
-	case errMethodNotAllowed:
-		nr.Handler405.ServeHTTP(w, r)
-	case nil:
-		if p != background {
-			ctx := context.WithValue(r.Context(), paramsKey, p)
-			r = r.WithContext(ctx)
-		}
-		h.ServeHTTP(w, r)
+	case l.notAllowed:
+		allowed := t.allowedMethods(host, p, nr.CaseInsensitive)
+		if nr.HandleOPTIONS && !hasMethod(allowed, OPTIONS) {
+			allowed = append(allowed, OPTIONS)
+			sortMethods(allowed)
+		}
+		w.Header().Set("Allow", joinMethods(allowed))
+		if method == OPTIONS && nr.HandleOPTIONS {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		ctx := context.WithValue(r.Context(), allowedMethodsKey, allowed)
+		nr.Handler405.ServeHTTP(w, r.WithContext(ctx))
 	default:
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		if nr.redirect(w, r, t, host) {
+			return
+		}
+		nr.Handler404.ServeHTTP(w, r)
+	}
+}
+
+// redirect replies a redirection to the canonical path of the request if
+// there is one, and reports whether it did.
+func (nr *NodeRouter) redirect(w http.ResponseWriter, r *http.Request, t *tree, host string) bool {
+	method := Method(r.Method)
+	p := r.URL.Path
+	if nr.UseEscapedPath {
+		p = r.URL.EscapedPath()
+	}
+	if method == CONNECT || p == "" || p[0] != '/' {
+		return false
+	}
+	origin := p
+	if nr.RedirectFixedPath {
+		p = cleanPath(p)
+	}
+	fold := nr.RedirectCaseInsensitive || nr.CaseInsensitive
+	fixed, ok := findPath(t, host, method, p, fold)
+	if !ok && nr.RedirectTrailingSlash && p != "/" {
+		if p[len(p)-1] == '/' {
+			fixed, ok = findPath(t, host, method, p[:len(p)-1], fold)
+		} else {
+			fixed, ok = findPath(t, host, method, p+"/", fold)
+		}
+	}
+	if !ok || fixed == origin {
+		return false
+	}
+	code := http.StatusPermanentRedirect
+	if method == GET || method == HEAD {
+		code = http.StatusMovedPermanently
+	}
+	u := url.URL{Path: fixed, RawQuery: r.URL.RawQuery}
+	if nr.UseEscapedPath {
+		u.Path, _ = url.PathUnescape(fixed)
+		u.RawPath = fixed
+	}
+	http.Redirect(w, r, u.String(), code)
+	return true
+}
+
+// findPath returns the path of the route matching host, method and p. If
+// fold is true, static text is compared ignoring case, and rewritten in the
+// case of the route.
+func findPath(t *tree, host string, method Method, p string, fold bool) (string, bool) {
+	l := &lookup{method: method, params: new(params), fold: fold}
+	if fold {
+		l.fixed = make([]byte, 0, len(p))
+	}
+	if t.match(l, host, p) == nil {
+		return "", false
+	}
+	if fold {
+		return string(l.fixed), true
+	}
+	return p, true
+}
+
+// cleanPath is like path.Clean, but keeps the trailing slash.
+func cleanPath(p string) string {
+	cleaned := path.Clean(p)
+	if p[len(p)-1] == '/' && cleaned != "/" {
+		cleaned += "/"
 	}
+	return cleaned
 }
 
-// AddRoute implements Router interface
+// AddRoute implements Router interface. It panics if the route can't be
+// added, see TryAddRoute.
 func (nr *NodeRouter) AddRoute(method Method, pattern string, handler http.Handler, filters ...Filter) {
-	nr.root.addRoute(method, pattern, MergeFilters(filters...).Do(handler))
+	if err := nr.TryAddRoute(method, pattern, handler, filters...); err != nil {
+		panic(err)
+	}
+}
+
+// TryAddRoute adds a route like AddRoute, but returns a *RouteError if the
+// pattern is malformed, or the route conflicts with an existing one.
+func (nr *NodeRouter) TryAddRoute(method Method, pattern string, handler http.Handler, filters ...Filter) error {
+	cfg, filters := splitOptions(filters)
+	rt := &route{
+		RouteInfo: RouteInfo{
+			Method:   method,
+			Host:     cfg.host,
+			Pattern:  pattern,
+			Params:   paramNames(pattern),
+			Name:     cfg.name,
+			Filters:  len(filters),
+			Matchers: len(cfg.matchers),
+		},
+		handler:  MergeFilters(filters...).Do(handler),
+		matchers: cfg.matchers,
+	}
+	if !validMethod(method) {
+		return rt.newError(nil, "invalid method")
+	}
+	if err := validatePattern(pattern); err != nil {
+		return rt.newError(nil, err.Error())
+	}
+	if cfg.host != "" {
+		labels, err := parseHost(cfg.host)
+		if err != nil {
+			return rt.newError(nil, err.Error())
+		}
+		ht := &hostTree{labels: labels}
+		rt.Params = append(ht.paramNames(), rt.Params...)
+	}
+	if name := duplicatedName(rt.Params); name != "" {
+		return rt.newError(nil, "duplicated param "+name)
+	}
+
+	nr.mu.Lock()
+	defer nr.mu.Unlock()
+	t := nr.load()
+	if existing, ok := t.names[rt.Name]; ok && (existing.Pattern != pattern || existing.Host != cfg.host) {
+		return rt.newError(existing, "duplicated route name "+rt.Name)
+	}
+	root := t.hostRoot(cfg.host)
+	if root == nil {
+		root = newNode("")
+	}
+	root = root.clone()
+	if err := root.addRoute(pattern, rt); err != nil {
+		return err
+	}
+	t, err := t.withRoot(cfg.host, root)
+	if err != nil {
+		return rt.newError(nil, err.Error())
+	}
+	if rt.Name != "" {
+		t.names = copyNames(t.names)
+		t.names[rt.Name] = rt
+	}
+	if len(rt.Params) > t.maxParams {
+		t.maxParams = len(rt.Params)
+	}
+	nr.tree.Store(t)
+	return nil
+}
+
+// RemoveRoute removes routes of method and pattern, whatever their matchers,
+// and reports whether they existed. Routes of a host are removed with the
+// WithHost option. It's safe to remove routes while serving requests.
+func (nr *NodeRouter) RemoveRoute(method Method, pattern string, opts ...RouteOption) bool {
+	cfg := new(routeConfig)
+	for _, opt := range opts {
+		opt.apply(cfg)
+	}
+
+	nr.mu.Lock()
+	defer nr.mu.Unlock()
+	t := nr.load()
+	root := t.hostRoot(cfg.host)
+	if root == nil {
+		return false
+	}
+	root, removed := root.removeRoute(method, pattern)
+	if removed == nil {
+		return false
+	}
+	t, _ = t.withRoot(cfg.host, root)
+	for _, rt := range removed {
+		if rt.Name == "" || t.names[rt.Name] != rt {
+			continue
+		}
+		t.names = copyNames(t.names)
+		delete(t.names, rt.Name)
+		// other methods of the same pattern may share the name.
+		t.walk(func(other *route) error {
+			if other.Name != rt.Name {
+				return nil
+			}
+			t.names[rt.Name] = other
+			return errFound
+		})
+	}
+	nr.tree.Store(t)
+	return true
+}
+
+func copyNames(names map[string]*route) map[string]*route {
+	c := make(map[string]*route, len(names)+1)
+	for name, rt := range names {
+		c[name] = rt
+	}
+	return c
+}
+
+// RouteError describes why a route can't be added.
+type RouteError struct {
+	Method  Method
+	Pattern string
+	Reason  string
+	// Conflict is the existing route conflicting with the new one, if any.
+	Conflict *RouteInfo
+}
+
+func (e *RouteError) Error() string {
+	msg := fmt.Sprintf("hodor: can't add route %s %s: %s", e.Method, e.Pattern, e.Reason)
+	if e.Conflict != nil {
+		msg += fmt.Sprintf(" (conflicts with %s %s)", e.Conflict.Method, e.Conflict.Pattern)
+	}
+	return msg
 }
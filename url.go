@@ -0,0 +1,97 @@
+/*
+ * Copyright 2017 Xuyuan Pang
+ * Author: Xuyuan Pang
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package hodor
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// URLBuilder is implemented by routers supporting named routes.
+type URLBuilder interface {
+	URL(name string, pairs ...string) (string, error)
+}
+
+// URL builds the escaped path of the route named name. The pairs are names
+// and values of params in the route pattern, like "id", "42". Values must
+// satisfy constraints of their params.
+func (nr *NodeRouter) URL(name string, pairs ...string) (string, error) {
+	rt, ok := nr.load().names[name]
+	if !ok {
+		return "", fmt.Errorf("hodor: no route named %s", name)
+	}
+	return buildURL(rt.Pattern, pairs...)
+}
+
+func buildURL(pattern string, pairs ...string) (string, error) {
+	if len(pairs)%2 != 0 {
+		return "", fmt.Errorf("hodor: odd number of params for %s", pattern)
+	}
+	values := make(map[string]string, len(pairs)/2)
+	for i := 0; i < len(pairs); i += 2 {
+		values[pairs[i]] = pairs[i+1]
+	}
+	var buf strings.Builder
+	for pattern != "" {
+		i := strings.IndexAny(pattern, ":*")
+		if i == -1 {
+			i = len(pattern)
+		}
+		buf.WriteString(escapeSegments(pattern[:i]))
+		pattern = pattern[i:]
+		if pattern == "" {
+			break
+		}
+		if pattern[0] == '*' {
+			name := pattern[1:]
+			value, ok := values[name]
+			if !ok {
+				return "", fmt.Errorf("hodor: missing param %s", name)
+			}
+			buf.WriteString(escapeSegments(value))
+			break
+		}
+		name, constraint, rest := parseParam(pattern)
+		value, ok := values[name]
+		if !ok || value == "" {
+			return "", fmt.Errorf("hodor: missing param %s", name)
+		}
+		if constraint != "" {
+			re, err := compileConstraint(constraint)
+			if err != nil {
+				return "", err
+			}
+			if !re.MatchString(value) {
+				return "", fmt.Errorf("hodor: param %s=%q doesn't match <%s>", name, value, constraint)
+			}
+		}
+		buf.WriteString(url.PathEscape(value))
+		pattern = rest
+	}
+	return buf.String(), nil
+}
+
+// escapeSegments escapes each segment of path, keeping slashes.
+func escapeSegments(path string) string {
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		segments[i] = url.PathEscape(segment)
+	}
+	return strings.Join(segments, "/")
+}
@@ -0,0 +1,50 @@
+package hodor
+
+import (
+	"testing"
+)
+
+func TestURL(t *testing.T) {
+	h := NewHodor(NewRouter())
+	h.Route().Group("/api").For(func(r Route) {
+		r.Get().Pattern("/users/:id<int>").Name("user.show").Handler(defaultHandler)
+		r.Get().Pattern("/files/:name.:ext").Name("file.show").Handler(defaultHandler)
+		r.Get().Pattern("/static/*filepath").Name("static").Handler(defaultHandler)
+	})
+
+	cases := []struct {
+		name  string
+		pairs []string
+		url   string
+		err   bool
+	}{
+		{"user.show", []string{"id", "42"}, "/api/users/42", false},
+		{"file.show", []string{"name", "my file", "ext", "json"}, "/api/files/my%20file.json", false},
+		{"file.show", []string{"name", "a/b", "ext", "json"}, "/api/files/a%2Fb.json", false},
+		{"static", []string{"filepath", "css/main file.css"}, "/api/static/css/main%20file.css", false},
+		{"static", []string{"filepath", ""}, "/api/static/", false},
+		{"user.show", []string{"id", "abc"}, "", true},
+		{"user.show", nil, "", true},
+		{"user.show", []string{"id"}, "", true},
+		{"file.show", []string{"name", "hodor"}, "", true},
+		{"user.delete", []string{"id", "42"}, "", true},
+	}
+	for _, c := range cases {
+		url, err := h.URL(c.name, c.pairs...)
+		if got, exp := err != nil, c.err; got != exp {
+			t.Errorf("%s %v error not match. exp: %v, got: %v", c.name, c.pairs, exp, err)
+		}
+		if got, exp := url, c.url; got != exp {
+			t.Errorf("%s %v url not match. exp: %s, got: %s", c.name, c.pairs, exp, got)
+		}
+	}
+
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Errorf("duplicated route name should be rejected")
+			}
+		}()
+		h.Route().Post().Pattern("/users").Name("user.show").Handler(defaultHandler)
+	}()
+}
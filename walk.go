@@ -0,0 +1,170 @@
+/*
+ * Copyright 2017 Xuyuan Pang
+ * Author: Xuyuan Pang
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package hodor
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+)
+
+// RouteInfo describes a registered route.
+type RouteInfo struct {
+	Method  Method   `json:"method"`
+	Host    string   `json:"host,omitempty"`
+	Pattern string   `json:"pattern"`
+	Params  []string `json:"params"`
+	Name    string   `json:"name,omitempty"`
+	Filters int      `json:"filters"`
+	// Matchers is the count of matchers, see Matcher.
+	Matchers int `json:"matchers,omitempty"`
+}
+
+// Walker is implemented by routers supporting route introspection.
+type Walker interface {
+	Walk(fn func(RouteInfo) error) error
+}
+
+// Walk calls fn for every route, routes without host first and then routes
+// of every host, ordered by pattern in the tree and then by method. It stops
+// at the first error returned by fn and returns it.
+func (nr *NodeRouter) Walk(fn func(RouteInfo) error) error {
+	return nr.load().walk(func(rt *route) error {
+		return fn(rt.RouteInfo)
+	})
+}
+
+// duplicatedName returns the first name appearing twice in names, or an
+// empty string if there is none.
+func duplicatedName(names []string) string {
+	for i, name := range names {
+		for _, prev := range names[:i] {
+			if prev == name {
+				return name
+			}
+		}
+	}
+	return ""
+}
+
+// paramNames returns names of params in pattern in path order.
+func paramNames(pattern string) []string {
+	names := []string{}
+	for {
+		i := strings.IndexAny(pattern, ":*")
+		if i == -1 {
+			return names
+		}
+		if pattern[i] == '*' {
+			return append(names, pattern[i+1:])
+		}
+		name, _, rest := parseParam(pattern[i:])
+		names = append(names, name)
+		pattern = rest
+	}
+}
+
+// WriteRoutes writes routes as an aligned text table.
+func WriteRoutes(w io.Writer, walker Walker) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "METHOD\tPATTERN\tNAME\tPARAMS\tFILTERS")
+	err := walker.Walk(func(info RouteInfo) error {
+		_, err := fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%d\n",
+			info.Method, info.Host+info.Pattern, info.Name, strings.Join(info.Params, ","), info.Filters)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	return tw.Flush()
+}
+
+// WriteRoutesJSON writes routes as an indented JSON array.
+func WriteRoutesJSON(w io.Writer, walker Walker) error {
+	infos := []RouteInfo{}
+	err := walker.Walk(func(info RouteInfo) error {
+		infos = append(infos, info)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	return enc.Encode(infos)
+}
+
+// WriteRoutesDOT writes routes as a Graphviz digraph of path segments, with
+// methods labeled on the segments ending routes.
+func WriteRoutesDOT(w io.Writer, walker Walker) error {
+	type segment struct {
+		id       int
+		label    string
+		methods  []string
+		children []*segment
+	}
+	root := &segment{label: "/"}
+	segments := []*segment{root}
+	err := walker.Walk(func(info RouteInfo) error {
+		s := root
+		for _, part := range strings.Split(strings.Trim(info.Host+info.Pattern, "/"), "/") {
+			if part == "" {
+				continue
+			}
+			var next *segment
+			for _, child := range s.children {
+				if child.label == part {
+					next = child
+					break
+				}
+			}
+			if next == nil {
+				next = &segment{id: len(segments), label: part}
+				segments = append(segments, next)
+				s.children = append(s.children, next)
+			}
+			s = next
+		}
+		s.methods = append(s.methods, info.Method.String())
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	var buf strings.Builder
+	buf.WriteString("digraph routes {\n\tnode [shape=box];\n")
+	for _, s := range segments {
+		label := s.label
+		if len(s.methods) > 0 {
+			label += "\n" + strings.Join(s.methods, " ")
+		}
+		fmt.Fprintf(&buf, "\tn%d [label=%s];\n", s.id, strconv.Quote(label))
+	}
+	for _, s := range segments {
+		for _, child := range s.children {
+			fmt.Fprintf(&buf, "\tn%d -> n%d;\n", s.id, child.id)
+		}
+	}
+	buf.WriteString("}\n")
+	_, err = io.WriteString(w, buf.String())
+	return err
+}
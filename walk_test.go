@@ -0,0 +1,95 @@
+package hodor
+
+import (
+	"bytes"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func newWalkRouter() *NodeRouter {
+	router := NewRouter()
+	noop := FilterFunc(func(next http.Handler) http.Handler { return next })
+	route := BuildRoute(router)
+	route.Post().Pattern("/users").Handler(defaultHandler)
+	route.Get().Pattern("/users").Handler(defaultHandler)
+	route.Group("/users").Filters(noop).For(func(r Route) {
+		r.Get().Pattern("/:id<int>").Name("user.show").Filters(noop).Handler(defaultHandler)
+		r.Delete().Pattern("/:id<int>").Handler(defaultHandler)
+	})
+	route.Get().Pattern("/files/:name.:ext").Handler(defaultHandler)
+	route.Get().Pattern("/static/*filepath").Name("static").Handler(defaultHandler)
+	return router
+}
+
+func TestWalk(t *testing.T) {
+	var infos []RouteInfo
+	err := newWalkRouter().Walk(func(info RouteInfo) error {
+		infos = append(infos, info)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	exp := []RouteInfo{
+		{GET, "", "/files/:name.:ext", []string{"name", "ext"}, "", 0, 0},
+		{GET, "", "/static/*filepath", []string{"filepath"}, "static", 0, 0},
+		{GET, "", "/users", []string{}, "", 0, 0},
+		{POST, "", "/users", []string{}, "", 0, 0},
+		{GET, "", "/users/:id<int>", []string{"id"}, "user.show", 2, 0},
+		{DELETE, "", "/users/:id<int>", []string{"id"}, "", 1, 0},
+	}
+	if len(infos) != len(exp) {
+		t.Fatalf("routes not match. exp: %v, got: %v", exp, infos)
+	}
+	for i := range exp {
+		got, exp := infos[i], exp[i]
+		if got.Method != exp.Method || got.Pattern != exp.Pattern || got.Name != exp.Name ||
+			got.Filters != exp.Filters || strings.Join(got.Params, ",") != strings.Join(exp.Params, ",") {
+			t.Errorf("route %d not match. exp: %v, got: %v", i, exp, got)
+		}
+	}
+}
+
+func TestWriteRoutes(t *testing.T) {
+	router := newWalkRouter()
+
+	var buf bytes.Buffer
+	if err := WriteRoutes(&buf, router); err != nil {
+		t.Fatal(err)
+	}
+	exp := `METHOD  PATTERN            NAME       PARAMS    FILTERS
+GET     /files/:name.:ext             name,ext  0
+GET     /static/*filepath  static     filepath  0
+GET     /users                                  0
+POST    /users                                  0
+GET     /users/:id<int>    user.show  id        2
+DELETE  /users/:id<int>               id        1
+`
+	if got := buf.String(); got != exp {
+		t.Errorf("text not match. exp:\n%s\ngot:\n%s", exp, got)
+	}
+
+	buf.Reset()
+	if err := WriteRoutesJSON(&buf, router); err != nil {
+		t.Fatal(err)
+	}
+	if got := buf.String(); !strings.Contains(got, `"pattern": "/users/:id<int>"`) ||
+		!strings.Contains(got, `"params": []`) {
+		t.Errorf("json not match. got:\n%s", got)
+	}
+
+	buf.Reset()
+	if err := WriteRoutesDOT(&buf, router); err != nil {
+		t.Fatal(err)
+	}
+	for _, line := range []string{
+		`n0 [label="/"];`,
+		`n6 [label=":id<int>\nGET DELETE"];`,
+		`n5 -> n6;`,
+	} {
+		if got := buf.String(); !strings.Contains(got, line) {
+			t.Errorf("dot should contain %s. got:\n%s", line, got)
+		}
+	}
+}